@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces path with data so that readers and crashes only
+// ever see the old or the new contents. The data is written to a sibling
+// temporary file, synced to disk and renamed over path, keeping the
+// original file's permissions.
+func writeFileAtomic(fs FileSystem, path string, data []byte) error {
+	perm := os.FileMode(0644)
+	info, err := fs.Stat(path)
+	if err == nil {
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error reading log file permissions: %w", err)
+	}
+	return writeFileAtomicPerm(fs, path, data, perm)
+}
+
+// writeFileAtomicPerm is writeFileAtomic with the permissions of the new
+// file given by the caller.
+func writeFileAtomicPerm(fs FileSystem, path string, data []byte, perm os.FileMode) error {
+
+	tmp, err := fs.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	fail := func(format string, err error) error {
+		_ = tmp.Close()
+		_ = fs.Remove(tmpName)
+		return fmt.Errorf(format, err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		return fail("error writing temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail("error syncing temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = fs.Remove(tmpName)
+		return fmt.Errorf("error closing temporary file: %w", err)
+	}
+	if err := fs.Chmod(tmpName, perm); err != nil {
+		_ = fs.Remove(tmpName)
+		return fmt.Errorf("error setting temporary file permissions: %w", err)
+	}
+	if err := fs.Rename(tmpName, path); err != nil {
+		_ = fs.Remove(tmpName)
+		return fmt.Errorf("error replacing log file: %w", err)
+	}
+
+	return nil
+}
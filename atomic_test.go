@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomic_Failures(t *testing.T) {
+	const logFile = "/tmp/test.log"
+
+	tests := []struct {
+		name       string
+		setupMock  func(*MockFileSystem)
+		errorMsg   string
+		leavesTemp bool // whether a temporary file was created and must be removed
+	}{
+		{
+			name:      "stat error",
+			setupMock: func(fs *MockFileSystem) { fs.statErr = errors.New("permission denied") },
+			errorMsg:  "error reading log file permissions",
+		},
+		{
+			name:      "create temp error",
+			setupMock: func(fs *MockFileSystem) { fs.createTempErr = errors.New("read-only file system") },
+			errorMsg:  "error creating temporary file",
+		},
+		{
+			name:       "write error",
+			setupMock:  func(fs *MockFileSystem) { fs.tempFile = &MockFile{writeErr: errors.New("no space left on device")} },
+			errorMsg:   "error writing temporary file",
+			leavesTemp: true,
+		},
+		{
+			name:       "sync error",
+			setupMock:  func(fs *MockFileSystem) { fs.tempFile = &MockFile{syncErr: errors.New("input/output error")} },
+			errorMsg:   "error syncing temporary file",
+			leavesTemp: true,
+		},
+		{
+			name:       "close error",
+			setupMock:  func(fs *MockFileSystem) { fs.tempFile = &MockFile{closeErr: errors.New("input/output error")} },
+			errorMsg:   "error closing temporary file",
+			leavesTemp: true,
+		},
+		{
+			name:       "chmod error",
+			setupMock:  func(fs *MockFileSystem) { fs.chmodErr = errors.New("operation not permitted") },
+			errorMsg:   "error setting temporary file permissions",
+			leavesTemp: true,
+		},
+		{
+			name:       "rename error",
+			setupMock:  func(fs *MockFileSystem) { fs.renameErr = errors.New("cross-device link") },
+			errorMsg:   "error replacing log file",
+			leavesTemp: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.readFiles[logFile] = []byte("old entry\n")
+			tt.setupMock(mockFS)
+
+			err := writeFileAtomic(mockFS, logFile, []byte("new entry\nold entry\n"))
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got nil", tt.errorMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+
+			if got := string(mockFS.readFiles[logFile]); got != "old entry\n" {
+				t.Errorf("Expected original content to be untouched, got %q", got)
+			}
+			if _, written := mockFS.writeFiles[logFile]; written {
+				t.Error("Expected log file not to be replaced")
+			}
+			if len(mockFS.tempFiles) != 0 {
+				t.Errorf("Expected temporary files to be removed, %d left", len(mockFS.tempFiles))
+			}
+			if tt.leavesTemp && len(mockFS.removed) != 1 {
+				t.Errorf("Expected temporary file to be removed once, got %v", mockFS.removed)
+			}
+			if mockFS.tempFile != nil && !mockFS.tempFile.closed {
+				t.Error("Expected temporary file to be closed")
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic_Mock(t *testing.T) {
+	const logFile = "/tmp/test.log"
+
+	mockFS := NewMockFileSystem()
+	mockFS.readFiles[logFile] = []byte("old entry\n")
+	mockFS.modes[logFile] = 0600
+	mockFS.tempFile = &MockFile{}
+
+	if err := writeFileAtomic(mockFS, logFile, []byte("new entry\nold entry\n")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := string(mockFS.writeFiles[logFile]); got != "new entry\nold entry\n" {
+		t.Errorf("Expected new content, got %q", got)
+	}
+	if !mockFS.tempFile.synced {
+		t.Error("Expected temporary file to be synced before rename")
+	}
+	if got := mockFS.modes[logFile]; got != 0600 {
+		t.Errorf("Expected mode 0600 to be kept, got %o", got)
+	}
+	if dir := filepath.Dir(mockFS.tempFile.name); dir != "/tmp" {
+		t.Errorf("Expected temporary file next to the log file, got %q", mockFS.tempFile.name)
+	}
+}
+
+func TestWriteFileAtomic_RealFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "app.log")
+	fs := &RealFileSystem{}
+
+	// A missing file is created with the usual log file permissions
+	if err := writeFileAtomic(fs, logFile, []byte("first\n")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	info, err := os.Stat(logFile)
+	if err != nil {
+		t.Fatalf("Expected log file to exist, got %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0644 {
+		t.Errorf("Expected mode 0644 for a new file, got %o", got)
+	}
+
+	// Existing permissions survive the replacement
+	if err := os.Chmod(logFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(fs, logFile, []byte("second\nfirst\n")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	info, err = os.Stat(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("Expected mode 0600 to be kept, got %o", got)
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "second\nfirst\n" {
+		t.Errorf("Expected replaced content, got %q", string(content))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the log file in %s, got %d entries", dir, len(entries))
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// compressedExt is added to the name of a rotated log file once it has
+// been compressed.
+const compressedExt = ".gz"
+
+// parseCompressAfter parses how old a rotated log file must be before it
+// is compressed. Empty means as soon as it is rotated.
+func parseCompressAfter(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid compress age %q: %w", value, err)
+	}
+	if age < 0 {
+		return 0, fmt.Errorf("invalid compress age %q: must not be negative", value)
+	}
+	return age, nil
+}
+
+// logBaseFiles returns the files entries are written to, oldest first:
+// every period file of a log rotated by time, or the log file itself.
+// Compressed period files are returned under their plain name.
+func logBaseFiles(fs FileSystem, config *Config) ([]string, error) {
+	pattern, err := newPeriodPattern(config)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == nil {
+		return []string{config.LogFile}, nil
+	}
+
+	files, err := pattern.files(fs)
+	if err != nil {
+		return nil, err
+	}
+	var bases []string
+	for _, file := range files {
+		base := strings.TrimSuffix(file, compressedExt)
+		if len(bases) > 0 && bases[len(bases)-1] == base {
+			continue
+		}
+		bases = append(bases, base)
+	}
+	return bases, nil
+}
+
+// logSegments returns every file holding entries of the log, oldest
+// first: for each base file, its numbered backups from the oldest down,
+// then the file itself. A compressed file is used when the plain one is
+// gone.
+func logSegments(fs FileSystem, config *Config) ([]string, error) {
+	bases, err := logBaseFiles(fs, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []string
+	for _, base := range bases {
+		backups, err := numberedBackups(fs, base)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(backups) - 1; i >= 0; i-- {
+			// Prefer the plain copy, which sorts before its compressed twin
+			if i > 0 && backups[i-1].n == backups[i].n {
+				continue
+			}
+			segments = append(segments, backups[i].path(base))
+		}
+
+		// A past period file may only exist compressed
+		if base != config.LogFile {
+			if _, err := fs.Stat(base); os.IsNotExist(err) {
+				segments = append(segments, base+compressedExt)
+				continue
+			}
+		}
+		segments = append(segments, base)
+	}
+	return segments, nil
+}
+
+// readSegment reads one file of the log, decompressing it if needed.
+func readSegment(fs FileSystem, path string) ([]byte, error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log file: %w", err)
+	}
+	if !strings.HasSuffix(path, compressedExt) {
+		return data, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s: %w", path, err)
+	}
+	defer reader.Close()
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// segment is a file of the log opened for reading at any offset.
+type segment struct {
+	io.ReaderAt
+	io.Closer
+	size int64
+}
+
+// openSegment opens a file of the log for reading. Compressed files are
+// decompressed into memory.
+func openSegment(fs FileSystem, path string) (*segment, error) {
+	if strings.HasSuffix(path, compressedExt) {
+		data, err := readSegment(fs, path)
+		if err != nil {
+			return nil, err
+		}
+		reader := bytes.NewReader(data)
+		return &segment{ReaderAt: reader, Closer: io.NopCloser(reader), size: reader.Size()}, nil
+	}
+
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading log file: %w", err)
+	}
+	return &segment{ReaderAt: file, Closer: file, size: info.Size()}, nil
+}
+
+// gzipBytes compresses the contents of the file at path, recording its
+// name and modification time in the gzip header.
+func gzipBytes(path string, modTime time.Time, data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	writer.Name = filepath.Base(path)
+	writer.ModTime = modTime
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// compressibleSegments returns the rotated files that are old enough to
+// be compressed: numbered backups and the period files of past periods.
+// current is the file being written to, which is never compressed.
+func compressibleSegments(fs FileSystem, config *Config, current string, now time.Time) ([]string, error) {
+	age, err := parseCompressAfter(config.CompressAfter)
+	if err != nil {
+		return nil, err
+	}
+	bases, err := logBaseFiles(fs, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	for _, base := range bases {
+		backups, err := numberedBackups(fs, base)
+		if err != nil {
+			return nil, err
+		}
+		for _, backup := range backups {
+			if !backup.compressed {
+				candidates = append(candidates, backup.path(base))
+			}
+		}
+		if base != current {
+			candidates = append(candidates, base)
+		}
+	}
+
+	var segments []string
+	for _, path := range candidates {
+		info, err := fs.Stat(path)
+		if err != nil {
+			continue // compressed already or removed by another process
+		}
+		if now.Sub(info.ModTime()) >= age {
+			segments = append(segments, path)
+		}
+	}
+	return segments, nil
+}
+
+// compressSegment replaces a rotated log file with a gzipped copy. The
+// file is compressed without holding the lock, so writers are not kept
+// waiting; if it was rotated further in the meantime it is left for a
+// later write to compress.
+func compressSegment(fs FileSystem, config *Config, path string) error {
+	before, err := fs.Stat(path)
+	if err != nil {
+		return nil
+	}
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	compressed, err := gzipBytes(path, before.ModTime(), data)
+	if err != nil {
+		return fmt.Errorf("error compressing %s: %w", path, err)
+	}
+
+	lock, err := lockLogFile(fs, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Close(); err != nil {
+			fmt.Printf("Warning: failed to release log file lock: %v\n", err)
+		}
+	}()
+
+	after, err := fs.Stat(path)
+	if err != nil || after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+		return nil
+	}
+
+	if err := writeFileAtomicPerm(fs, path+compressedExt, compressed, before.Mode().Perm()); err != nil {
+		return fmt.Errorf("error compressing %s: %w", path, err)
+	}
+	if err := fs.Remove(path); err != nil {
+		return fmt.Errorf("error removing %s after compressing it: %w", path, err)
+	}
+	return nil
+}
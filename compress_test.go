@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadSegment(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.readFiles["/logs/app.log.1"] = []byte("plain\n")
+	mockFS.readFiles["/logs/app.log.2.gz"] = gzipData(t, "compressed\n")
+	mockFS.readFiles["/logs/app.log.3.gz"] = []byte("not gzip")
+
+	tests := []struct {
+		path     string
+		expected string
+		errorMsg string
+	}{
+		{path: "/logs/app.log.1", expected: "plain\n"},
+		{path: "/logs/app.log.2.gz", expected: "compressed\n"},
+		{path: "/logs/app.log.3.gz", errorMsg: "error decompressing"},
+		{path: "/logs/app.log.4", errorMsg: "error reading log file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			data, err := readSegment(mockFS, tt.path)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, string(data))
+			}
+		})
+	}
+}
+
+func TestLogSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		files    []string
+		expected []string
+	}{
+		{
+			name:   "numbered backups oldest first",
+			config: Config{LogFile: "/logs/app.log"},
+			files:  []string{"/logs/app.log", "/logs/app.log.1", "/logs/app.log.2.gz", "/logs/app.log.3", "/logs/app.log.3.gz"},
+			expected: []string{
+				"/logs/app.log.3",
+				"/logs/app.log.2.gz",
+				"/logs/app.log.1",
+				"/logs/app.log",
+			},
+		},
+		{
+			name:     "missing log file",
+			config:   Config{LogFile: "/logs/app.log"},
+			expected: []string{"/logs/app.log"},
+		},
+		{
+			name:   "period files with backups",
+			config: Config{LogFile: "/logs/app-%Y-%m-%d.log"},
+			files: []string{
+				"/logs/app-2024-01-15.log",
+				"/logs/app-2024-01-15.log.1.gz",
+				"/logs/app-2024-01-14.log.gz",
+				"/logs/app-2024-01-13.log",
+				"/logs/app-2024-01-13.log.gz",
+			},
+			expected: []string{
+				"/logs/app-2024-01-13.log",
+				"/logs/app-2024-01-14.log.gz",
+				"/logs/app-2024-01-15.log.1.gz",
+				"/logs/app-2024-01-15.log",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			for _, file := range tt.files {
+				mockFS.readFiles[file] = []byte("entry\n")
+			}
+
+			segments, err := logSegments(mockFS, &tt.config)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if strings.Join(segments, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, segments)
+			}
+		})
+	}
+}
+
+func TestCompressibleSegments(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	for _, file := range []string{
+		"/logs/app-2024-01-15.log",
+		"/logs/app-2024-01-15.log.1",
+		"/logs/app-2024-01-15.log.2.gz",
+		"/logs/app-2024-01-14.log",
+		"/logs/app-2024-01-13.log.gz",
+	} {
+		mockFS.readFiles[file] = []byte("entry\n")
+	}
+	config := &Config{LogFile: "/logs/app-%Y-%m-%d.log", Compress: true}
+
+	segments, err := compressibleSegments(mockFS, config, "/logs/app-2024-01-15.log", time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []string{"/logs/app-2024-01-14.log", "/logs/app-2024-01-15.log.1"}
+	if strings.Join(segments, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, segments)
+	}
+}
+
+// lockHookFileSystem runs a function when the lock is taken, to act as
+// another process between compressing a file and replacing it.
+type lockHookFileSystem struct {
+	*MockFileSystem
+	onLock func()
+}
+
+func (fs *lockHookFileSystem) Lock(path string, timeout time.Duration) (io.Closer, error) {
+	if fs.onLock != nil {
+		fs.onLock()
+	}
+	return fs.MockFileSystem.Lock(path, timeout)
+}
+
+func TestCompressSegment(t *testing.T) {
+	config := &Config{LogFile: "/logs/app.log", Compress: true}
+
+	t.Run("replaces the file", func(t *testing.T) {
+		mockFS := NewMockFileSystem()
+		mockFS.readFiles["/logs/app.log.1"] = []byte("rotated entry\n")
+		mockFS.modes["/logs/app.log.1"] = 0600
+
+		if err := compressSegment(mockFS, config, "/logs/app.log.1"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, exists := mockFS.readFiles["/logs/app.log.1"]; exists {
+			t.Error("Expected plain file to be removed")
+		}
+		data, err := readSegment(mockFS, "/logs/app.log.1.gz")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if string(data) != "rotated entry\n" {
+			t.Errorf("Expected compressed copy of the file, got %q", string(data))
+		}
+		if got := mockFS.modes["/logs/app.log.1.gz"]; got != 0600 {
+			t.Errorf("Expected mode 0600 to be kept, got %o", got)
+		}
+	})
+
+	t.Run("leaves a file rotated meanwhile", func(t *testing.T) {
+		mockFS := NewMockFileSystem()
+		mockFS.readFiles["/logs/app.log.1"] = []byte("rotated entry\n")
+		hooked := &lockHookFileSystem{MockFileSystem: mockFS, onLock: func() {
+			mockFS.readFiles["/logs/app.log.2"] = mockFS.readFiles["/logs/app.log.1"]
+			mockFS.readFiles["/logs/app.log.1"] = []byte("newer rotated entry, longer\n")
+		}}
+
+		if err := compressSegment(hooked, config, "/logs/app.log.1"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, exists := mockFS.readFiles["/logs/app.log.1.gz"]; exists {
+			t.Error("Expected no compressed copy of a file that changed")
+		}
+		if string(mockFS.readFiles["/logs/app.log.1"]) != "newer rotated entry, longer\n" {
+			t.Error("Expected the new file to be left alone")
+		}
+	})
+}
+
+func TestLogService_Compression(t *testing.T) {
+	tests := []struct {
+		name           string
+		compressAfter  string
+		expectCompress bool
+	}{
+		{name: "right after rotation", expectCompress: true},
+		{name: "not old enough yet", compressAfter: "1h", expectCompress: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := NewTempHomeFileSystem(t)
+			logFile := filepath.Join(fs.home, "app.log")
+			printer := &MockPrinter{}
+			configService := NewConfigService(fs, printer)
+			logService := NewLogService(configService, fs, printer)
+
+			compress := true
+			err := configService.UpdateConfig(ConfigUpdate{
+				LogFile:       logFile,
+				MaxSize:       "100",
+				Compress:      &compress,
+				CompressAfter: tt.compressAfter,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			for i := 1; i <= 6; i++ {
+				// A new service per entry, as each entry is its own slog run
+				logService = NewLogService(configService, fs, printer)
+				if err := logService.AppendLog("info", fmt.Sprintf("entry %d padded to about fifty bytes", i)); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if err := logService.Wait(); err != nil {
+					t.Fatalf("Expected compression to succeed, got %v", err)
+				}
+			}
+
+			backups, err := filepath.Glob(logFile + ".*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rotated int
+			for _, backup := range backups {
+				if strings.HasSuffix(backup, ".lock") {
+					continue
+				}
+				rotated++
+				if strings.HasSuffix(backup, compressedExt) != tt.expectCompress {
+					t.Errorf("Expected compressed=%v for %s", tt.expectCompress, backup)
+				}
+			}
+			if rotated == 0 {
+				t.Fatal("Expected the log to be rotated")
+			}
+
+			printer.Reset()
+			if err := logService.ViewLog(ViewOptions{Quiet: true}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			output := strings.Join(printer.GetMessages(), "")
+			position := -1
+			for i := 1; i <= 6; i++ {
+				next := strings.Index(output, fmt.Sprintf("entry %d ", i))
+				if next <= position {
+					t.Fatalf("Expected all entries in order, got %q", output)
+				}
+				position = next
+			}
+		})
+	}
+}
+
+func TestConfigService_Compress(t *testing.T) {
+	on, off := true, false
+
+	tests := []struct {
+		name          string
+		compress      *bool
+		compressAfter string
+		expected      bool
+		expectError   bool
+	}{
+		{name: "turn on", compress: &on, expected: true},
+		{name: "with age", compress: &on, compressAfter: "24h", expected: true},
+		{name: "turn off", compress: &off, expected: false},
+		{name: "unchanged", compress: nil, expected: true},
+		{name: "invalid age", compress: &on, compressAfter: "tomorrow", expectError: true},
+		{name: "negative age", compress: &on, compressAfter: "-1h", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(Config{LogFile: "/tmp/test.log", Compress: true})
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+
+			configService := NewConfigService(mockFS, &MockPrinter{})
+			err := configService.UpdateConfig(ConfigUpdate{Compress: tt.compress, CompressAfter: tt.compressAfter})
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			config, err := configService.LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Compress != tt.expected {
+				t.Errorf("Expected compress %v, got %v", tt.expected, config.Compress)
+			}
+		})
+	}
+}
+
+func TestOptionalBool(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: nil, expected: "unset"},
+		{args: []string{"--compress"}, expected: "true"},
+		{args: []string{"--compress=false"}, expected: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var value optionalBool
+			flags := flag.NewFlagSet("config", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			flags.Var(&value, "compress", "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			got := "unset"
+			if value.value != nil {
+				got = fmt.Sprint(*value.value)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRotateLogFile_Compressed(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.readFiles["/logs/app.log"] = []byte("current\n")
+	mockFS.readFiles["/logs/app.log.1"] = []byte("backup 1\n")
+	mockFS.readFiles["/logs/app.log.2.gz"] = []byte("backup 2\n")
+	mockFS.readFiles["/logs/app.log.3.gz"] = []byte("backup 3\n")
+
+	if err := rotateLogFile(mockFS, "/logs/app.log", 3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"/logs/app.log.1":    "current\n",
+		"/logs/app.log.2":    "backup 1\n",
+		"/logs/app.log.3.gz": "backup 2\n",
+	}
+	for path, content := range expected {
+		if got := string(mockFS.readFiles[path]); got != content {
+			t.Errorf("Expected %s to contain %q, got %q", path, content, got)
+		}
+	}
+	for _, path := range []string{"/logs/app.log", "/logs/app.log.2.gz", "/logs/app.log.4.gz"} {
+		if _, exists := mockFS.readFiles[path]; exists {
+			t.Errorf("Expected %s not to exist", path)
+		}
+	}
+}
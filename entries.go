@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxRecordLineSize bounds a single line read from a log file. A message
+// read from stdin as a whole may be one long line.
+const maxRecordLineSize = 64 * 1024 * 1024
+
+// Record is one record of a log file: its text, the line it starts on,
+// and the entry parsed from it or the reason it could not be parsed.
+type Record struct {
+	Line  int
+	Text  string
+	Entry Entry
+	Err   error
+}
+
+// Malformed reports whether the record holds text that is not an entry.
+// Blank lines are not counted as malformed.
+func (r Record) Malformed() bool {
+	return r.Err != nil && strings.TrimSpace(r.Text) != ""
+}
+
+// EntryScanner reads the records of a log file one at a time, joining
+// continuation lines onto the line they belong to, and parses each one.
+// A record that cannot be parsed is returned with its error rather than
+// ending the scan, so a damaged line does not hide the entries after it.
+type EntryScanner struct {
+	lines   *bufio.Scanner
+	parser  Formatter
+	line    int    // number of the last line read
+	next    string // a line read ahead, starting the next record
+	hasNext bool
+	record  Record
+
+	malformed      int
+	firstMalformed Record
+}
+
+// NewEntryScanner returns a scanner reading records from r and parsing
+// them with parser.
+func NewEntryScanner(r io.Reader, parser Formatter) *EntryScanner {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	return &EntryScanner{lines: lines, parser: parser}
+}
+
+// Scan advances to the next record, returning false at the end of the
+// input or on a read error.
+func (s *EntryScanner) Scan() bool {
+	if !s.hasNext {
+		if !s.lines.Scan() {
+			return false
+		}
+		s.line++
+		s.next = s.lines.Text()
+	}
+
+	record := Record{Line: s.line, Text: s.next}
+	s.hasNext = false
+	for s.lines.Scan() {
+		s.line++
+		line := s.lines.Text()
+		if !strings.HasPrefix(line, continuationPrefix) {
+			s.next, s.hasNext = line, true
+			break
+		}
+		record.Text += "\n" + line
+	}
+
+	record.Entry, record.Err = s.parser.Parse(record.Text)
+	if record.Malformed() {
+		if s.malformed == 0 {
+			s.firstMalformed = record
+		}
+		s.malformed++
+	}
+	s.record = record
+	return true
+}
+
+// Record returns the record read by the last call to Scan.
+func (s *EntryScanner) Record() Record {
+	return s.record
+}
+
+// Err returns the error that stopped the scan, if it was not the end of
+// the input.
+func (s *EntryScanner) Err() error {
+	return s.lines.Err()
+}
+
+// Malformed returns the number of records read so far that could not be
+// parsed.
+func (s *EntryScanner) Malformed() int {
+	return s.malformed
+}
+
+// FirstMalformed returns the first record read that could not be parsed.
+func (s *EntryScanner) FirstMalformed() (Record, bool) {
+	return s.firstMalformed, s.malformed > 0
+}
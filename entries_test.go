@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEntryScanner(t *testing.T) {
+	text, err := NewTextFormatter("", defaultTimestampFormat(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		data              string
+		expectedLines     []int
+		expectedMessages  []string // "" for a record that does not parse
+		expectedMalformed int
+	}{
+		{
+			name:             "single entries",
+			data:             "[2024-01-15 10:30:00] INFO: first\n[2024-01-15 10:31:00] WARN: second\n",
+			expectedLines:    []int{1, 2},
+			expectedMessages: []string{"first", "second"},
+		},
+		{
+			name:             "multi-line entry",
+			data:             "[2024-01-15 10:30:00] ERROR: panic\n  goroutine 1\n  \tmain.go:10\n[2024-01-15 10:31:00] INFO: next\n",
+			expectedLines:    []int{1, 4},
+			expectedMessages: []string{"panic\ngoroutine 1\n\tmain.go:10", "next"},
+		},
+		{
+			name:              "malformed lines are reported",
+			data:              "garbage\n[2024-01-15 10:30:00] INFO: first\n[not a time] INFO: x\n[2024-01-15 10:31:00] INFO: second",
+			expectedLines:     []int{1, 2, 3, 4},
+			expectedMessages:  []string{"", "first", "", "second"},
+			expectedMalformed: 2,
+		},
+		{
+			name:             "blank lines are not malformed",
+			data:             "[2024-01-15 10:30:00] INFO: first\n\n[2024-01-15 10:31:00] INFO: second\n",
+			expectedLines:    []int{1, 2, 3},
+			expectedMessages: []string{"first", "", "second"},
+		},
+		{
+			name:              "continuation without an entry",
+			data:              "  stray\n[2024-01-15 10:30:00] INFO: first\n",
+			expectedLines:     []int{1, 2},
+			expectedMessages:  []string{"", "first"},
+			expectedMalformed: 1,
+		},
+		{
+			name: "empty input",
+			data: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewEntryScanner(strings.NewReader(tt.data), text)
+			var lines []int
+			var messages []string
+			for scanner.Scan() {
+				record := scanner.Record()
+				lines = append(lines, record.Line)
+				if record.Err != nil {
+					messages = append(messages, "")
+				} else {
+					messages = append(messages, record.Entry.Message)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(lines) != len(tt.expectedLines) {
+				t.Fatalf("Expected records at lines %v, got %v", tt.expectedLines, lines)
+			}
+			for i := range lines {
+				if lines[i] != tt.expectedLines[i] || messages[i] != tt.expectedMessages[i] {
+					t.Errorf("Expected record %d at line %d with %q, got line %d with %q", i, tt.expectedLines[i], tt.expectedMessages[i], lines[i], messages[i])
+				}
+			}
+			if scanner.Malformed() != tt.expectedMalformed {
+				t.Errorf("Expected %d malformed records, got %d", tt.expectedMalformed, scanner.Malformed())
+			}
+		})
+	}
+}
+
+func TestEntryScanner_FirstMalformed(t *testing.T) {
+	text, err := NewTextFormatter("", defaultTimestampFormat(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	scanner := NewEntryScanner(strings.NewReader("[2024-01-15 10:30:00] INFO: ok\nbroken one\nbroken two\n"), text)
+	for scanner.Scan() {
+	}
+
+	first, ok := scanner.FirstMalformed()
+	if !ok || first.Line != 2 || first.Text != "broken one" || first.Err == nil {
+		t.Errorf("Expected the record at line 2 with its error, got %+v", first)
+	}
+}
+
+func TestLogService_ViewLog_Malformed(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         ViewOptions
+		expectedOutput  string
+		expectedWarning string
+	}{
+		{
+			name:            "shown as stored",
+			options:         ViewOptions{},
+			expectedOutput:  "[2024-01-15 10:30:00] INFO: ok\nnot an entry\n[2024-01-15 10:31:00] INFO service=api: also ok\n",
+			expectedWarning: "1 records could not be parsed and are shown as stored, the first at /tmp/test.log:2",
+		},
+		{
+			name:            "skipped when filtering",
+			options:         ViewOptions{Fields: map[string]string{"service": "api"}},
+			expectedOutput:  "[2024-01-15 10:31:00] INFO service=api: also ok\n",
+			expectedWarning: "Skipped 1 records that could not be parsed, the first at /tmp/test.log:2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			config := Config{LogFile: "/tmp/test.log", FileOrder: FileOrderOldestFirst}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readFiles["/tmp/test.log"] = []byte("[2024-01-15 10:30:00] INFO: ok\nnot an entry\n[2024-01-15 10:31:00] INFO service=api: also ok\n")
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+			if err := logService.ViewLog(tt.options); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if !mockPrinter.ContainsMessage(tt.expectedOutput) {
+				t.Errorf("Expected output %q, got %v", tt.expectedOutput, mockPrinter.GetMessages())
+			}
+			if !mockPrinter.ContainsMessage(tt.expectedWarning) {
+				t.Errorf("Expected warning %q, got %v", tt.expectedWarning, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	FormatText   = "text"
+	FormatJSONL  = "jsonl"
+	FormatLogfmt = "logfmt"
+)
+
+const timestampLayout = "2006-01-02 15:04:05"
+
+// DefaultTemplate reproduces the original plain text layout. Fields are
+// placed before the message so that free-form message text cannot be
+// mistaken for them.
+const DefaultTemplate = "[{{.Time}}] {{.Level}}{{.Fields}}: {{.Message}}"
+
+// Entry is a single log record.
+type Entry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Host    string
+	Fields  map[string]string
+}
+
+// FormatOptions holds the configuration that shapes how entries are written.
+type FormatOptions struct {
+	Template   string
+	TimeFormat string
+	TimeZone   string
+}
+
+// Formatter converts entries to and from the records stored in the log
+// file. A record is a single line, followed by continuation lines when
+// the text format writes a multi-line message.
+type Formatter interface {
+	Format(entry Entry) (string, error)
+	Parse(record string) (Entry, error)
+}
+
+// continuationPrefix starts every additional line of a multi-line message
+// in the text format. Lines starting with it belong to the previous entry.
+const continuationPrefix = "  "
+
+// NewFormatter returns the formatter for the given format name.
+// An empty name selects the plain text format.
+func NewFormatter(format string, options FormatOptions) (Formatter, error) {
+	stamp, err := NewTimestampFormat(options.TimeFormat, options.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "", FormatText:
+		return NewTextFormatter(options.Template, stamp)
+	case FormatJSONL:
+		return &JSONLFormatter{stamp: stamp}, nil
+	case FormatLogfmt:
+		return &LogfmtFormatter{stamp: stamp}, nil
+	default:
+		return nil, fmt.Errorf("format must be 'text', 'jsonl' or 'logfmt'")
+	}
+}
+
+// TextFormatter writes entries using a text/template line layout and
+// reads them back with a pattern derived from the same template.
+type TextFormatter struct {
+	stamp        *TimestampFormat
+	tmpl         *template.Template
+	pattern      *regexp.Regexp
+	placeholders []string
+}
+
+// templateData is the value a line template is executed against. Fields
+// is rendered with a leading space, or empty when the entry has no fields.
+type templateData struct {
+	Time    string
+	Level   string
+	Message string
+	Host    string
+	Fields  string
+}
+
+// logfmtPairPattern matches a single key=value pair as written by encodeLogfmt.
+const logfmtPairPattern = `[^\s="]+=(?:"(?:[^"\\]|\\.)*"|[^\s"]*)`
+
+// templatePlaceholders maps each supported placeholder to the pattern that
+// matches its rendered value.
+var templatePlaceholders = map[string]string{
+	"Time":    `(.+?)`,
+	"Level":   `(\S+?)`,
+	"Message": `(.*?)`,
+	"Host":    `(\S*?)`,
+	"Fields":  `((?: ` + logfmtPairPattern + `)*)`,
+}
+
+// NewTextFormatter compiles a line template. An empty template selects
+// DefaultTemplate.
+func NewTextFormatter(text string, stamp *TimestampFormat) (*TextFormatter, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+
+	tmpl, err := template.New("line").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	var pattern strings.Builder
+	var placeholders []string
+	pattern.WriteString("(?s)^")
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			pattern.WriteString(regexp.QuoteMeta(string(node.Text)))
+		case *parse.ActionNode:
+			name, ok := placeholderName(node)
+			if !ok {
+				return nil, fmt.Errorf("invalid template: unsupported action %s, use {{.Time}}, {{.Level}}, {{.Message}}, {{.Host}} or {{.Fields}}", node)
+			}
+			pattern.WriteString(templatePlaceholders[name])
+			placeholders = append(placeholders, name)
+		default:
+			return nil, fmt.Errorf("invalid template: unsupported action %s, use {{.Time}}, {{.Level}}, {{.Message}}, {{.Host}} or {{.Fields}}", node)
+		}
+	}
+	pattern.WriteString("$")
+
+	if startsWithSpace(tmpl.Tree.Root.Nodes) {
+		return nil, fmt.Errorf("invalid template: lines must not start with whitespace or {{.Fields}}")
+	}
+
+	for _, required := range []string{"Time", "Message"} {
+		if !slices.Contains(placeholders, required) {
+			return nil, fmt.Errorf("invalid template: {{.%s}} is required", required)
+		}
+	}
+
+	return &TextFormatter{
+		stamp:        stamp,
+		tmpl:         tmpl,
+		pattern:      regexp.MustCompile(pattern.String()),
+		placeholders: placeholders,
+	}, nil
+}
+
+// startsWithSpace reports whether a template renders lines that may start
+// with whitespace.
+func startsWithSpace(nodes []parse.Node) bool {
+	if len(nodes) == 0 {
+		return false
+	}
+	switch node := nodes[0].(type) {
+	case *parse.TextNode:
+		return len(node.Text) > 0 && (node.Text[0] == ' ' || node.Text[0] == '\t')
+	case *parse.ActionNode:
+		name, _ := placeholderName(node)
+		return name == "Fields"
+	}
+	return false
+}
+
+// placeholderName reports which field a {{.Name}} action refers to.
+func placeholderName(node *parse.ActionNode) (string, bool) {
+	if len(node.Pipe.Decl) > 0 || len(node.Pipe.Cmds) != 1 || len(node.Pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	field, ok := node.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 {
+		return "", false
+	}
+	if _, ok := templatePlaceholders[field.Ident[0]]; !ok {
+		return "", false
+	}
+	return field.Ident[0], true
+}
+
+func (f *TextFormatter) Format(entry Entry) (string, error) {
+	var b strings.Builder
+	err := f.tmpl.Execute(&b, templateData{
+		Time:    f.stamp.Format(entry.Time),
+		Level:   entry.Level,
+		Message: entry.Message,
+		Host:    entry.Host,
+		Fields:  templateFields(entry.Fields),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error executing template: %w", err)
+	}
+
+	line := b.String()
+	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		return "", fmt.Errorf("entry %q starts with whitespace and would be read as part of the previous entry", line)
+	}
+	return strings.ReplaceAll(line, "\n", "\n"+continuationPrefix) + "\n", nil
+}
+
+func (f *TextFormatter) Parse(record string) (Entry, error) {
+	line := strings.ReplaceAll(record, "\n"+continuationPrefix, "\n")
+	matches := f.pattern.FindStringSubmatch(line)
+	if matches == nil {
+		return Entry{}, fmt.Errorf("line does not match template")
+	}
+
+	var entry Entry
+	seen := make(map[string]bool)
+	for i, name := range f.placeholders {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		value := matches[i+1]
+		switch name {
+		case "Time":
+			timestamp, err := f.stamp.Parse(value)
+			if err != nil {
+				return Entry{}, err
+			}
+			entry.Time = timestamp
+		case "Level":
+			entry.Level = value
+		case "Message":
+			entry.Message = value
+		case "Host":
+			entry.Host = value
+		case "Fields":
+			fields, err := parseFields(value)
+			if err != nil {
+				return Entry{}, err
+			}
+			entry.Fields = fields
+		}
+	}
+
+	return entry, nil
+}
+
+// fieldPairs returns fields as logfmt pairs sorted by key.
+func fieldPairs(fields map[string]string) []logfmtPair {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]logfmtPair, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, logfmtPair{Key: key, Value: fields[key]})
+	}
+	return pairs
+}
+
+// formatFields renders fields as logfmt pairs sorted by key.
+func formatFields(fields map[string]string) string {
+	return encodeLogfmt(fieldPairs(fields))
+}
+
+func templateFields(fields map[string]string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return " " + formatFields(fields)
+}
+
+func parseFields(s string) (map[string]string, error) {
+	pairs, err := decodeLogfmt(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields: %w", err)
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	fields := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		fields[pair.Key] = pair.Value
+	}
+	return fields, nil
+}
+
+// ValidateFormat checks that entries written with the given format and
+// options can be read back by the view parser.
+func ValidateFormat(format string, options FormatOptions) error {
+	formatter, err := NewFormatter(format, options)
+	if err != nil {
+		return err
+	}
+
+	// The message ends in a key=value token, which a template putting the
+	// fields right after the message would read back as a field
+	sample := Entry{
+		Time:    time.Date(2023, 11, 24, 21, 47, 38, 0, time.Local),
+		Level:   "WARN",
+		Message: "sample: \"quoted\" message with [brackets]\n\tand a second line target=prod",
+		Host:    "example-host",
+		Fields:  map[string]string{"service": "api", "note": "two words"},
+	}
+
+	line, err := formatter.Format(sample)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := formatter.Parse(strings.TrimSuffix(line, "\n"))
+	if err != nil {
+		return fmt.Errorf("entries written as %q cannot be read back: %w", strings.TrimSuffix(line, "\n"), err)
+	}
+
+	text, isText := formatter.(*TextFormatter)
+	has := func(name string) bool { return !isText || slices.Contains(text.placeholders, name) }
+
+	switch {
+	case !parsed.Time.Equal(sample.Time):
+		return fmt.Errorf("entries written as %q do not read back the same timestamp, check the time format", strings.TrimSuffix(line, "\n"))
+	case has("Level") && parsed.Level != sample.Level:
+		return fmt.Errorf("entries written as %q do not read back the same level", strings.TrimSuffix(line, "\n"))
+	case parsed.Message != sample.Message:
+		return fmt.Errorf("entries written as %q do not read back the same message", strings.TrimSuffix(line, "\n"))
+	case isText && has("Host") && parsed.Host != sample.Host:
+		return fmt.Errorf("entries written as %q do not read back the same host", strings.TrimSuffix(line, "\n"))
+	case has("Fields") && formatFields(parsed.Fields) != formatFields(sample.Fields):
+		return fmt.Errorf("entries written as %q do not read back the same fields", strings.TrimSuffix(line, "\n"))
+	}
+
+	return nil
+}
+
+// reservedFieldKeys are the keys the logfmt format uses for the entry itself.
+var reservedFieldKeys = []string{"time", "level", "msg"}
+
+var fieldKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ValidateFields checks that field keys can be written by every format.
+func ValidateFields(fields map[string]string) error {
+	for key, value := range fields {
+		if !fieldKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid field key %q: use letters, digits, '_', '.' or '-'", key)
+		}
+		if slices.Contains(reservedFieldKeys, key) {
+			return fmt.Errorf("field key %q is reserved", key)
+		}
+		if !utf8.ValidString(value) {
+			return fmt.Errorf("field %q contains invalid UTF-8", key)
+		}
+	}
+	return nil
+}
+
+type JSONLFormatter struct {
+	stamp *TimestampFormat
+}
+
+type jsonlRecord struct {
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+func (f *JSONLFormatter) Format(entry Entry) (string, error) {
+	data, err := json.Marshal(jsonlRecord{
+		Timestamp: f.stamp.Format(entry.Time),
+		Level:     entry.Level,
+		Message:   entry.Message,
+		Fields:    entry.Fields,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding entry: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+func (f *JSONLFormatter) Parse(line string) (Entry, error) {
+	var record jsonlRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return Entry{}, fmt.Errorf("invalid JSON entry: %w", err)
+	}
+
+	timestamp, err := f.stamp.Parse(record.Timestamp)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{Time: timestamp, Level: record.Level, Message: record.Message, Fields: record.Fields}, nil
+}
+
+type LogfmtFormatter struct {
+	stamp *TimestampFormat
+}
+
+func (f *LogfmtFormatter) Format(entry Entry) (string, error) {
+	pairs := []logfmtPair{
+		{Key: "time", Value: f.stamp.Format(entry.Time)},
+		{Key: "level", Value: entry.Level},
+		{Key: "msg", Value: entry.Message},
+	}
+	pairs = append(pairs, fieldPairs(entry.Fields)...)
+	return encodeLogfmt(pairs) + "\n", nil
+}
+
+func (f *LogfmtFormatter) Parse(line string) (Entry, error) {
+	pairs, err := decodeLogfmt(line)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var entry Entry
+	var timestamp string
+	for _, pair := range pairs {
+		switch pair.Key {
+		case "time":
+			timestamp = pair.Value
+		case "level":
+			entry.Level = pair.Value
+		case "msg":
+			entry.Message = pair.Value
+		default:
+			if entry.Fields == nil {
+				entry.Fields = make(map[string]string)
+			}
+			entry.Fields[pair.Key] = pair.Value
+		}
+	}
+
+	if timestamp == "" {
+		return Entry{}, fmt.Errorf("missing time key")
+	}
+	entry.Time, err = f.stamp.Parse(timestamp)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
+
+type logfmtPair struct {
+	Key   string
+	Value string
+}
+
+// encodeLogfmt renders pairs as space separated key=value tokens. Values
+// that are empty or contain spaces, '=', quotes or control characters are
+// quoted and escaped.
+func encodeLogfmt(pairs []logfmtPair) string {
+	tokens := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		tokens = append(tokens, pair.Key+"="+quoteLogfmtValue(pair.Value))
+	}
+	return strings.Join(tokens, " ")
+}
+
+func quoteLogfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !strconv.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
+
+// decodeLogfmt splits a logfmt line into its key=value pairs, unquoting
+// quoted values.
+func decodeLogfmt(line string) ([]logfmtPair, error) {
+	var pairs []logfmtPair
+	rest := strings.TrimLeft(line, " ")
+
+	for rest != "" {
+		eq := strings.IndexAny(rest, "= ")
+		if eq <= 0 || rest[eq] != '=' {
+			return nil, fmt.Errorf("invalid logfmt pair near %q", rest)
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted value for key %q", key)
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value for key %q: %w", key, err)
+			}
+			value = unquoted
+			rest = rest[end+1:]
+			if rest != "" && rest[0] != ' ' {
+				return nil, fmt.Errorf("unexpected characters after value for key %q", key)
+			}
+		} else {
+			end := strings.IndexByte(rest, ' ')
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+
+		pairs = append(pairs, logfmtPair{Key: key, Value: value})
+		rest = strings.TrimLeft(rest, " ")
+	}
+
+	return pairs, nil
+}
+
+// closingQuote returns the index of the quote that terminates the quoted
+// string at the start of s, or -1 if there is none.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// splitRecords splits the contents of a log file into records, joining
+// continuation lines onto the line they belong to.
+func splitRecords(data string) []string {
+	var records []string
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		if len(records) > 0 && strings.HasPrefix(line, continuationPrefix) {
+			records[len(records)-1] += "\n" + line
+			continue
+		}
+		records = append(records, line)
+	}
+	return records
+}
+
+// reverseRecords returns data with its records in reverse order, keeping
+// continuation lines with the record they belong to.
+func reverseRecords(data string) string {
+	if data == "" {
+		return ""
+	}
+	records := splitRecords(data)
+	var b strings.Builder
+	b.Grow(len(data) + 1)
+	for i := len(records) - 1; i >= 0; i-- {
+		b.WriteString(records[i])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderedRecord is a record as 'slog view' shows it, with the time used
+// to merge it with the records of other files: its entry's timestamp, or
+// for a record that could not be parsed, that of the entry before it.
+type renderedRecord struct {
+	Time time.Time
+	Text string
+}
+
+// renderEntries reads records and writes each entry back out with
+// renderer, returning one rendered record per record. When match is set, only
+// matching entries are kept; otherwise records that cannot be parsed are
+// kept as they are. When search is set, only entries whose text it
+// matches are kept, with the matches highlighted.
+func renderEntries(records *EntryScanner, renderer Formatter, match func(Entry) bool, search *Search) ([]renderedRecord, error) {
+	var rendered []renderedRecord
+	var last time.Time
+	for records.Scan() {
+		record := records.Record()
+		if record.Err != nil {
+			if match == nil {
+				rendered = append(rendered, renderedRecord{Time: last, Text: record.Text + "\n"})
+			}
+			continue
+		}
+		last = record.Entry.Time
+		if match != nil && !match(record.Entry) {
+			continue
+		}
+		text, err := renderer.Format(record.Entry)
+		if err != nil {
+			text = record.Text + "\n"
+		}
+		if search != nil {
+			if !search.Matches(text) {
+				continue
+			}
+			text = search.Highlight(text)
+		}
+		rendered = append(rendered, renderedRecord{Time: record.Entry.Time, Text: text})
+	}
+	return rendered, records.Err()
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func defaultTimestampFormat(t *testing.T) *TimestampFormat {
+	t.Helper()
+	stamp, err := NewTimestampFormat("", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return stamp
+}
+
+// Test NewFormatter
+func TestNewFormatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		expectError bool
+	}{
+		{name: "empty defaults to text", format: ""},
+		{name: "text", format: "text"},
+		{name: "jsonl", format: "jsonl"},
+		{name: "logfmt", format: "logfmt"},
+		{name: "unknown format", format: "xml", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.format, FormatOptions{})
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if formatter == nil {
+				t.Error("Expected formatter, got nil")
+			}
+		})
+	}
+}
+
+// Test that every format can read back what it writes
+func TestFormatter_RoundTrip(t *testing.T) {
+	entry := Entry{
+		Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+		Level:   "WARN",
+		Message: `disk "/var" at 91%: check it, mount=/var`,
+		Fields:  map[string]string{"service": "api", "url": "http://example.com:8080/", "note": `say "hi"`},
+	}
+
+	for _, format := range []string{FormatText, FormatJSONL, FormatLogfmt} {
+		t.Run(format, func(t *testing.T) {
+			formatter, err := NewFormatter(format, FormatOptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			line, err := formatter.Format(entry)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+				t.Errorf("Expected a single newline-terminated line, got %q", line)
+			}
+
+			parsed, err := formatter.Parse(strings.TrimSuffix(line, "\n"))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !parsed.Time.Equal(entry.Time) {
+				t.Errorf("Expected time %v, got %v", entry.Time, parsed.Time)
+			}
+			if parsed.Level != entry.Level {
+				t.Errorf("Expected level %q, got %q", entry.Level, parsed.Level)
+			}
+			if parsed.Message != entry.Message {
+				t.Errorf("Expected message %q, got %q", entry.Message, parsed.Message)
+			}
+			if formatFields(parsed.Fields) != formatFields(entry.Fields) {
+				t.Errorf("Expected fields %v, got %v", entry.Fields, parsed.Fields)
+			}
+		})
+	}
+}
+
+func TestFormatter_MultiLine(t *testing.T) {
+	entry := Entry{
+		Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+		Level:   "ERROR",
+		Message: "panic: boom\n\ngoroutine 1 [running]:\n\tmain.main()\n  indented: line",
+		Fields:  map[string]string{"service": "api"},
+	}
+
+	for _, format := range []string{FormatText, FormatJSONL, FormatLogfmt} {
+		t.Run(format, func(t *testing.T) {
+			formatter, err := NewFormatter(format, FormatOptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			first, err := formatter.Format(entry)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			second, err := formatter.Format(Entry{Time: entry.Time, Level: "INFO", Message: "next"})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			records := splitRecords(first + second)
+			if len(records) != 2 {
+				t.Fatalf("Expected 2 records, got %d: %q", len(records), records)
+			}
+
+			parsed, err := formatter.Parse(records[0])
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if parsed.Message != entry.Message {
+				t.Errorf("Expected message %q, got %q", entry.Message, parsed.Message)
+			}
+			if parsed.Fields["service"] != "api" {
+				t.Errorf("Expected fields %v, got %v", entry.Fields, parsed.Fields)
+			}
+		})
+	}
+}
+
+func TestTextFormatter_MultiLineLayout(t *testing.T) {
+	formatter, err := NewTextFormatter("", defaultTimestampFormat(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	line, err := formatter.Format(Entry{
+		Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+		Level:   "ERROR",
+		Message: "first\nsecond\n\tthird",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "[2024-01-15 10:30:00] ERROR: first\n  second\n  \tthird\n"
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+}
+
+func TestFormatter_Fields(t *testing.T) {
+	entry := Entry{
+		Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+		Level:   "ERROR",
+		Message: "deploy failed",
+		Fields:  map[string]string{"service": "api", "region": "eu"},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   FormatText,
+			expected: "[2024-01-15 10:30:00] ERROR region=eu service=api: deploy failed\n",
+		},
+		{
+			format:   FormatJSONL,
+			expected: `{"timestamp":"2024-01-15 10:30:00","level":"ERROR","message":"deploy failed","fields":{"region":"eu","service":"api"}}` + "\n",
+		},
+		{
+			format:   FormatLogfmt,
+			expected: `time="2024-01-15 10:30:00" level=ERROR msg="deploy failed" region=eu service=api` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.format, FormatOptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			line, err := formatter.Format(entry)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		errorMsg string
+	}{
+		{name: "no fields", fields: nil},
+		{name: "valid keys", fields: map[string]string{"service": "api", "http.status": "500", "trace-id": "abc", "_x": ""}},
+		{name: "key with space", fields: map[string]string{"my key": "v"}, errorMsg: "invalid field key"},
+		{name: "key starting with digit", fields: map[string]string{"1st": "v"}, errorMsg: "invalid field key"},
+		{name: "reserved key", fields: map[string]string{"msg": "v"}, errorMsg: "reserved"},
+		{name: "invalid UTF-8 value", fields: map[string]string{"k": string([]byte{0xff})}, errorMsg: "invalid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFields(tt.fields)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+			} else if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestNewTextFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		errorMsg string
+	}{
+		{name: "default template", template: ""},
+		{name: "all placeholders", template: "{{.Time}} {{.Host}} [{{.Level}}] {{.Message}} {{.Fields}}"},
+		{name: "syntax error", template: "{{.Time", errorMsg: "invalid template"},
+		{name: "unknown placeholder", template: "{{.Time}} {{.User}} {{.Message}}", errorMsg: "unsupported action"},
+		{name: "control structure", template: "{{.Time}} {{if .Level}}{{.Level}}{{end}} {{.Message}}", errorMsg: "unsupported action"},
+		{name: "function call", template: "{{.Time}} {{printf \"%s\" .Message}}", errorMsg: "unsupported action"},
+		{name: "missing time", template: "{{.Level}}: {{.Message}}", errorMsg: "{{.Time}} is required"},
+		{name: "missing message", template: "{{.Time}} {{.Level}}", errorMsg: "{{.Message}} is required"},
+		{name: "leading whitespace", template: "  {{.Time}} {{.Message}}", errorMsg: "must not start with whitespace"},
+		{name: "leading fields", template: "{{.Fields}} {{.Time}} {{.Message}}", errorMsg: "must not start with whitespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTextFormatter(tt.template, defaultTimestampFormat(t))
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+			} else if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestTextFormatter_Template(t *testing.T) {
+	formatter, err := NewTextFormatter("{{.Time}} {{.Host}} <{{.Level}}> {{.Message}} |{{.Fields}}", defaultTimestampFormat(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	entry := Entry{
+		Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+		Level:   "ERROR",
+		Message: "deploy failed | rolling back",
+		Host:    "web-1",
+		Fields:  map[string]string{"service": "api", "region": "eu west"},
+	}
+
+	line, err := formatter.Format(entry)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := `2024-01-15 10:30:00 web-1 <ERROR> deploy failed | rolling back | region="eu west" service=api` + "\n"
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+
+	parsed, err := formatter.Parse(strings.TrimSuffix(line, "\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if parsed.Host != entry.Host || parsed.Level != entry.Level || parsed.Message != entry.Message {
+		t.Errorf("Expected %+v, got %+v", entry, parsed)
+	}
+	if formatFields(parsed.Fields) != formatFields(entry.Fields) {
+		t.Errorf("Expected fields %v, got %v", entry.Fields, parsed.Fields)
+	}
+
+	if _, err := formatter.Parse("[2024-01-15 10:30:00] INFO: other layout"); err == nil {
+		t.Error("Expected error parsing a line in another layout, got nil")
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		template    string
+		expectError bool
+	}{
+		{name: "default text", format: "text"},
+		{name: "jsonl", format: "jsonl"},
+		{name: "legacy layout", format: "text", template: "{{.Time}} - {{.Host}} - {{.Level}} - {{.Message}}"},
+		{name: "adjacent placeholders cannot be split", format: "text", template: "{{.Time}}{{.Level}}{{.Message}}", expectError: true},
+		{name: "fields right after the message", format: "text", template: "[{{.Time}}] {{.Level}}: {{.Message}}{{.Fields}}", expectError: true},
+		{name: "fields after a separator", format: "text", template: "[{{.Time}}] {{.Level}}: {{.Message}} |{{.Fields}}"},
+		{name: "unknown format", format: "csv", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFormat(tt.format, FormatOptions{Template: tt.template})
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestJSONLFormatter_Format(t *testing.T) {
+	formatter := &JSONLFormatter{stamp: defaultTimestampFormat(t)}
+	line, err := formatter.Format(Entry{
+		Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+		Level:   "INFO",
+		Message: "Application started",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var record map[string]string
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+
+	expected := map[string]string{
+		"timestamp": "2024-01-15 10:30:00",
+		"level":     "INFO",
+		"message":   "Application started",
+	}
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("Expected %s %q, got %q", k, v, record[k])
+		}
+	}
+}
+
+func TestLogfmtFormatter_Format(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "bare word",
+			message:  "started",
+			expected: `time="2024-01-15 10:30:00" level=INFO msg=started` + "\n",
+		},
+		{
+			name:     "spaces are quoted",
+			message:  "Application started",
+			expected: `time="2024-01-15 10:30:00" level=INFO msg="Application started"` + "\n",
+		},
+		{
+			name:     "quotes and backslashes are escaped",
+			message:  `path "C:\tmp"`,
+			expected: `time="2024-01-15 10:30:00" level=INFO msg="path \"C:\\tmp\""` + "\n",
+		},
+		{
+			name:     "newlines are escaped",
+			message:  "line one\nline two",
+			expected: `time="2024-01-15 10:30:00" level=INFO msg="line one\nline two"` + "\n",
+		},
+		{
+			name:     "equals sign is quoted",
+			message:  "a=b",
+			expected: `time="2024-01-15 10:30:00" level=INFO msg="a=b"` + "\n",
+		},
+		{
+			name:     "empty message",
+			message:  "",
+			expected: `time="2024-01-15 10:30:00" level=INFO msg=""` + "\n",
+		},
+	}
+
+	formatter := &LogfmtFormatter{stamp: defaultTimestampFormat(t)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := formatter.Format(Entry{
+				Time:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local),
+				Level:   "INFO",
+				Message: tt.message,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+
+			parsed, err := formatter.Parse(strings.TrimSuffix(line, "\n"))
+			if err != nil {
+				t.Fatalf("Expected no error parsing %q, got %v", line, err)
+			}
+			if parsed.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, parsed.Message)
+			}
+		})
+	}
+}
+
+func TestDecodeLogfmt(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []logfmtPair
+		expectError bool
+	}{
+		{
+			name:     "plain and quoted values",
+			input:    `level=warn msg="disk full" host=web-1`,
+			expected: []logfmtPair{{"level", "warn"}, {"msg", "disk full"}, {"host", "web-1"}},
+		},
+		{
+			name:     "empty value",
+			input:    `a= b=""`,
+			expected: []logfmtPair{{"a", ""}, {"b", ""}},
+		},
+		{
+			name:        "missing equals",
+			input:       `level warn`,
+			expectError: true,
+		},
+		{
+			name:        "unterminated quote",
+			input:       `msg="oops`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := decodeLogfmt(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(pairs) != len(tt.expected) {
+				t.Fatalf("Expected %d pairs, got %d: %v", len(tt.expected), len(pairs), pairs)
+			}
+			for i, pair := range tt.expected {
+				if pairs[i] != pair {
+					t.Errorf("Expected pair %v, got %v", pair, pairs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEntries(t *testing.T) {
+	data := `{"timestamp":"2024-01-15 10:30:00","level":"INFO","message":"Test message"}
+not json at all
+{"timestamp":"2024-01-15 10:31:00","level":"WARN","message":"Warning message"}
+{"timestamp":"2024-01-15 10:32:00","level":"ERROR","message":"Stack trace\n\tmain.go:10"}
+`
+	text, err := NewTextFormatter("", defaultTimestampFormat(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	records := NewEntryScanner(strings.NewReader(data), &JSONLFormatter{stamp: defaultTimestampFormat(t)})
+	rendered, err := renderEntries(records, text, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var output string
+	for _, record := range rendered {
+		output += record.Text
+	}
+	if records.Malformed() != 1 {
+		t.Errorf("Expected 1 malformed record, got %d", records.Malformed())
+	}
+
+	expected := "[2024-01-15 10:30:00] INFO: Test message\n" +
+		"not json at all\n" +
+		"[2024-01-15 10:31:00] WARN: Warning message\n" +
+		"[2024-01-15 10:32:00] ERROR: Stack trace\n  \tmain.go:10\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
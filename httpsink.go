@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults for sinks that send entries over HTTP.
+const (
+	defaultHTTPBatchSize = 100
+	defaultHTTPTimeout   = 5 * time.Second
+	defaultHTTPRetries   = 3
+)
+
+// httpRetryBackoff is the wait before the first retry of a failed
+// request; it doubles for each further retry.
+var httpRetryBackoff = 250 * time.Millisecond
+
+// validateHTTPSink checks the settings shared by sinks that send entries
+// over HTTP.
+func validateHTTPSink(s SinkConfig) error {
+	if s.URL == "" {
+		return fmt.Errorf("%s sink requires a url", s.kind())
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q: use an http:// or https:// address", s.URL)
+	}
+	for name := range s.Headers {
+		if name == "" || strings.ContainsAny(name, " \t:\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if s.BatchSize < 0 {
+		return fmt.Errorf("batch size must not be negative")
+	}
+	if s.Retries != nil && *s.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if _, err := parseHTTPTimeout(s.Timeout); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseHTTPTimeout parses how long an HTTP sink may spend delivering
+// entries, falling back to defaultHTTPTimeout when it is empty.
+func parseHTTPTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultHTTPTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", value)
+	}
+	return timeout, nil
+}
+
+// spoolPath returns the file undelivered entries for a sink are kept in
+// until the next run, named after the sink's type and target.
+func spoolPath(homeDir string, sink SinkConfig) string {
+	sum := sha256.Sum256([]byte(sink.kind() + ":" + sink.target()))
+	return filepath.Join(homeDir, ".slog", "spool", sink.kind()+"-"+hex.EncodeToString(sum[:8])+".jsonl")
+}
+
+// spoolRecord is an entry as stored in a spool file.
+type spoolRecord struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Host    string            `json:"host,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// errRejected marks a request the endpoint refused; sending it again
+// would not help, so its entries are dropped rather than spooled.
+var errRejected = errors.New("rejected")
+
+// httpEncoder turns a batch of entries into a request body and its
+// content type.
+type httpEncoder func(entries []Entry) ([]byte, string, error)
+
+// httpSink sends entries to an HTTP endpoint in batches. Entries are
+// queued by Write and sent once a batch is full and when the sink is
+// closed. A failed request is retried with exponential backoff within the
+// sink's timeout; entries that still cannot be delivered are appended to a
+// spool file and sent before new entries the next time the sink is used.
+type httpSink struct {
+	fs        FileSystem
+	sink      SinkConfig
+	name      string
+	encode    httpEncoder
+	gzip      bool
+	client    *http.Client
+	spool     string
+	batchSize int
+	timeout   time.Duration
+	retries   int
+
+	pending  []Entry
+	drained  bool  // the spool left by earlier runs has been sent
+	down     bool  // delivery failed this run; later batches go to the spool
+	lastErr  error // why the endpoint was given up on
+	rejected []error
+	spooled  int
+}
+
+// newHTTPSink prepares an HTTP sink; nothing is sent until a batch fills
+// up or the sink is closed. name describes the sink in output, such as
+// "webhook https://hooks.example.com/logs".
+func newHTTPSink(fs FileSystem, sink SinkConfig, name string, encode httpEncoder, compress bool) (*httpSink, error) {
+	homeDir, err := fs.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	timeout, err := parseHTTPTimeout(sink.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	batchSize := sink.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultHTTPBatchSize
+	}
+	retries := defaultHTTPRetries
+	if sink.Retries != nil {
+		retries = *sink.Retries
+	}
+
+	return &httpSink{
+		fs:        fs,
+		sink:      sink,
+		name:      name,
+		encode:    encode,
+		gzip:      compress,
+		client:    &http.Client{},
+		spool:     spoolPath(homeDir, sink),
+		batchSize: batchSize,
+		timeout:   timeout,
+		retries:   retries,
+	}, nil
+}
+
+func (s *httpSink) Write(entry Entry) (string, error) {
+	if !s.drained {
+		// Entries left over from earlier runs go first
+		if err := s.drainSpool(); err != nil {
+			return "", err
+		}
+		s.drained = true
+	}
+
+	s.pending = append(s.pending, entry)
+	if len(s.pending) >= s.batchSize {
+		if err := s.flush(len(s.pending) / s.batchSize * s.batchSize); err != nil {
+			return "", err
+		}
+	}
+	return s.name, nil
+}
+
+// Close sends the entries still queued. Entries that could not be
+// delivered are reported as an error, as are entries the endpoint
+// rejected.
+func (s *httpSink) Close() error {
+	if err := s.flush(len(s.pending)); err != nil {
+		return err
+	}
+
+	var errs []error
+	if s.spooled > 0 {
+		errs = append(errs, fmt.Errorf("%d entries could not be sent to %s: %w; they are kept in %s and sent on the next run", s.spooled, s.name, s.lastErr, s.spool))
+	}
+	errs = append(errs, s.rejected...)
+	return errors.Join(errs...)
+}
+
+// flush sends the first n pending entries in batches. Once the endpoint
+// fails, the remaining entries are spooled without trying it again, so a
+// down endpoint costs each run at most one timeout.
+func (s *httpSink) flush(n int) error {
+	entries := s.pending[:n]
+	s.pending = s.pending[n:]
+
+	deadline := time.Now().Add(s.timeout)
+	for len(entries) > 0 && !s.down {
+		batch := entries[:min(len(entries), s.batchSize)]
+		err := s.deliver(batch, deadline)
+		if errors.Is(err, errRejected) {
+			s.rejected = append(s.rejected, fmt.Errorf("%s dropped %d entries: %w", s.name, len(batch), err))
+		} else if err != nil {
+			s.down = true
+			s.lastErr = err
+			break
+		}
+		entries = entries[len(batch):]
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := s.appendSpool(entries); err != nil {
+		return err
+	}
+	s.spooled += len(entries)
+	return nil
+}
+
+// deliver sends one batch, retrying failed requests with exponential
+// backoff until the retries or the time before deadline run out.
+func (s *httpSink) deliver(batch []Entry, deadline time.Time) error {
+	body, contentType, err := s.encode(batch)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	if s.gzip {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write(body); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		body = compressed.Bytes()
+	}
+
+	backoff := httpRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = s.post(body, contentType, deadline)
+		if err == nil || errors.Is(err, errRejected) {
+			return err
+		}
+		if attempt >= s.retries || time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes one request. Timeouts, 408, 429 and 5xx responses may
+// succeed when retried; other failed responses are rejections.
+func (s *httpSink) post(body []byte, contentType string, deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for name, value := range s.sink.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if message := strings.TrimSpace(string(detail)); message != "" {
+		return fmt.Errorf("%w with %s: %s", errRejected, resp.Status, message)
+	}
+	return fmt.Errorf("%w with %s", errRejected, resp.Status)
+}
+
+// drainSpool sends the entries earlier runs left in the spool file. The
+// file stays on disk while they are sent and is rewritten without each
+// batch once the endpoint has taken or rejected it, so a run that is
+// killed part way sends a batch again next time rather than losing it.
+// The spool lock is held throughout, so concurrent runs wait instead of
+// sending the same entries.
+func (s *httpSink) drainSpool() error {
+	lock, err := s.lockSpool()
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	data, err := s.fs.ReadFile(s.spool)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading spool file: %w", err)
+	}
+
+	var lines [][]byte
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*maxStdinLineSize)
+	for scanner.Scan() {
+		var record spoolRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A line cut short by a crash; the rest of the spool is intact
+			continue
+		}
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+		entries = append(entries, Entry{
+			Time:    record.Time,
+			Level:   record.Level,
+			Message: record.Message,
+			Host:    record.Host,
+			Fields:  record.Fields,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading spool file: %w", err)
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	for len(entries) > 0 {
+		batch := entries[:min(len(entries), s.batchSize)]
+		err := s.deliver(batch, deadline)
+		if errors.Is(err, errRejected) {
+			s.rejected = append(s.rejected, fmt.Errorf("%s dropped %d entries: %w", s.name, len(batch), err))
+		} else if err != nil {
+			s.down = true
+			s.lastErr = err
+			s.spooled += len(entries)
+			return nil
+		}
+		entries = entries[len(batch):]
+		lines = lines[len(batch):]
+		if err := s.rewriteSpool(lines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteSpool replaces the spool file with the lines of the entries not
+// yet sent, removing it when there are none.
+func (s *httpSink) rewriteSpool(lines [][]byte) error {
+	if len(lines) == 0 {
+		if err := s.fs.Remove(s.spool); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing spool file: %w", err)
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := writeFileAtomicPerm(s.fs, s.spool, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing spool file: %w", err)
+	}
+	return nil
+}
+
+// appendSpool adds undelivered entries to the sink's spool file.
+func (s *httpSink) appendSpool(entries []Entry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(spoolRecord{
+			Time:    entry.Time,
+			Level:   entry.Level,
+			Message: entry.Message,
+			Host:    entry.Host,
+			Fields:  entry.Fields,
+		})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	lock, err := s.lockSpool()
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	file, err := s.fs.OpenFile(s.spool, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening spool file: %w", err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing spool file: %w", err)
+	}
+	return file.Close()
+}
+
+// lockSpool takes the lock guarding the sink's spool file, waiting long
+// enough for another run to send the spooled entries.
+func (s *httpSink) lockSpool() (io.Closer, error) {
+	if err := s.fs.MkdirAll(filepath.Dir(s.spool), 0755); err != nil {
+		return nil, fmt.Errorf("error creating spool directory: %w", err)
+	}
+	lock, err := s.fs.Lock(lockPath(s.spool), s.timeout+DefaultLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error locking spool file: %w", err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultJournalSocket is journald's native protocol socket, used when a
+// journald sink has no address.
+const defaultJournalSocket = "/run/systemd/journal/socket"
+
+// journalFieldLimit is the longest field name journald accepts.
+const journalFieldLimit = 64
+
+// journalReservedFields are set from the entry itself, so entry fields
+// with the same name are renamed.
+var journalReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"SYSLOG_FACILITY":   true,
+}
+
+// validateJournaldSink checks the settings of a journald sink.
+func validateJournaldSink(s SinkConfig) error {
+	if strings.Contains(s.Address, "://") {
+		return fmt.Errorf("invalid journal socket %q: give the path of journald's socket", s.Address)
+	}
+	if s.Facility != "" {
+		if _, ok := syslogFacilities[s.Facility]; !ok {
+			return fmt.Errorf("unknown syslog facility %q: use kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp or local0 to local7", s.Facility)
+		}
+	}
+	return nil
+}
+
+// journalFieldName turns an entry field key into a journal field name:
+// upper case letters, digits and underscores, not starting with an
+// underscore or digit, which journald reserves or rejects.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') || journalReservedFields[name] {
+		name = "FIELD_" + name
+	}
+	if len(name) > journalFieldLimit {
+		name = name[:journalFieldLimit]
+	}
+	return name
+}
+
+// appendJournalField adds one field in the native protocol: NAME=value
+// and a newline, or for values containing a newline, the name, a newline,
+// the value's length as a little-endian 64-bit integer, the value and a
+// newline.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// formatJournal renders an entry as a native protocol datagram.
+func formatJournal(entry Entry, priority int, identifier string, facility string) []byte {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", entry.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(priority))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", identifier)
+	if facility != "" {
+		appendJournalField(&buf, "SYSLOG_FACILITY", strconv.Itoa(syslogFacilities[facility]))
+	}
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		appendJournalField(&buf, journalFieldName(key), entry.Fields[key])
+	}
+	return buf.Bytes()
+}
+
+// journaldSink sends entries to journald over its native datagram socket.
+type journaldSink struct {
+	config *Config
+	sink   SinkConfig
+	conn   net.Conn
+}
+
+// newJournaldSink connects to the journal socket of a sink.
+func newJournaldSink(config *Config, sink SinkConfig) (*journaldSink, error) {
+	conn, err := net.DialTimeout("unixgram", sink.target(), syslogTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the journal at %s: %w", sink.target(), err)
+	}
+	return &journaldSink{config: config, sink: sink, conn: conn}, nil
+}
+
+func (s *journaldSink) Write(entry Entry) (string, error) {
+	severity, ok := s.config.severity(entry.Level)
+	if !ok {
+		severity = levelSeverities["info"]
+	}
+	identifier := s.sink.AppName
+	if identifier == "" {
+		identifier = defaultSyslogAppName
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err != nil {
+		return "", fmt.Errorf("error sending to the journal at %s: %w", s.sink.target(), err)
+	}
+	if _, err := s.conn.Write(formatJournal(entry, severity, identifier, s.sink.Facility)); err != nil {
+		return "", fmt.Errorf("error sending to the journal at %s: %w", s.sink.target(), err)
+	}
+	return "journal " + s.sink.target(), nil
+}
+
+func (s *journaldSink) Close() error {
+	return s.conn.Close()
+}
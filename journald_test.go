@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseJournal decodes a native protocol datagram into its fields.
+func parseJournal(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i]
+		}
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			data = data[len(line)+1:]
+			continue
+		}
+
+		data = data[len(line)+1:]
+		if len(data) < 8 {
+			t.Fatalf("Expected a value length after %q", line)
+		}
+		size := binary.LittleEndian.Uint64(data[:8])
+		data = data[8:]
+		if uint64(len(data)) < size+1 || data[size] != '\n' {
+			t.Fatalf("Expected %d bytes and a newline after %q", size, line)
+		}
+		fields[string(line)] = string(data[:size])
+		data = data[size+1:]
+	}
+	return fields
+}
+
+func TestJournalFieldName(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "service", expected: "SERVICE"},
+		{key: "request-id", expected: "REQUEST_ID"},
+		{key: "_pid", expected: "FIELD__PID"},
+		{key: "2fa", expected: "FIELD_2FA"},
+		{key: "message", expected: "FIELD_MESSAGE"},
+		{key: "café", expected: "CAF_"},
+		{key: strings.Repeat("a", 70), expected: strings.Repeat("A", 64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := journalFieldName(tt.key); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatJournal(t *testing.T) {
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   "ERROR",
+		Message: "Deploy failed\npanic: boom",
+		Fields:  map[string]string{"service": "api", "message": "shadowed"},
+	}
+
+	data := formatJournal(entry, 3, "deploy", "local0")
+	if !bytes.HasPrefix(data, []byte("MESSAGE\n")) {
+		t.Errorf("Expected a multi-line message in the length-prefixed form, got %q", data)
+	}
+
+	expected := map[string]string{
+		"MESSAGE":           "Deploy failed\npanic: boom",
+		"PRIORITY":          "3",
+		"SYSLOG_IDENTIFIER": "deploy",
+		"SYSLOG_FACILITY":   "16",
+		"SERVICE":           "api",
+		"FIELD_MESSAGE":     "shadowed",
+	}
+	fields := parseJournal(t, data)
+	if len(fields) != len(expected) {
+		t.Errorf("Expected %d fields, got %v", len(expected), fields)
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, fields[name])
+		}
+	}
+}
+
+func TestLogService_JournaldSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix datagram sockets are not supported on Windows")
+	}
+
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	config := Config{
+		Sinks: []SinkConfig{
+			{Path: "/logs/app.log"},
+			{Type: SinkJournald, Address: socket, AppName: "maintenance"},
+		},
+		LogLevels: map[string]LevelConfig{"success": {Flag: "s", Severity: "notice"}},
+		FileOrder: FileOrderOldestFirst,
+	}
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	if err := logService.AppendLogWithFields("success", "Vacuum finished", map[string]string{"table": "events"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.Close(); err != nil {
+		t.Fatalf("Expected no error closing sinks, got %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a journal datagram, got %v", err)
+	}
+
+	fields := parseJournal(t, buf[:n])
+	expected := map[string]string{
+		"MESSAGE":           "Vacuum finished",
+		"PRIORITY":          "5",
+		"SYSLOG_IDENTIFIER": "maintenance",
+		"TABLE":             "events",
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, fields[name])
+		}
+	}
+	if !mockPrinter.ContainsMessage("Logged to /logs/app.log, journal " + socket) {
+		t.Errorf("Expected output naming both sinks, got %v", mockPrinter.GetMessages())
+	}
+}
+
+func TestConfigService_JournaldSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		sink     SinkConfig
+		errorMsg string
+	}{
+		{name: "default socket", sink: SinkConfig{Type: SinkJournald}},
+		{name: "custom socket", sink: SinkConfig{Type: SinkJournald, Address: "/tmp/journal.sock", Facility: "daemon"}},
+		{name: "network address", sink: SinkConfig{Type: SinkJournald, Address: "udp://logs:514"}, errorMsg: "invalid journal socket"},
+		{name: "unknown facility", sink: SinkConfig{Type: SinkJournald, Facility: "web"}, errorMsg: "unknown syslog facility"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configService := NewConfigService(mockFS, &MockPrinter{})
+
+			err := configService.UpdateConfig(ConfigUpdate{AddSinks: []SinkConfig{tt.sink}})
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
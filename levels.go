@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LevelConfig is one configured log level: the flag that selects it and,
+// optionally, a file its entries go to instead of the main log file and
+// the syslog severity of a level without a standard name.
+type LevelConfig struct {
+	Flag     string `json:"flag"`
+	File     string `json:"file,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// levelConfigJSON has the fields of LevelConfig without its JSON methods.
+type levelConfigJSON LevelConfig
+
+// MarshalJSON writes a level with nothing but a flag as the flag alone,
+// the form earlier versions used.
+func (l LevelConfig) MarshalJSON() ([]byte, error) {
+	if l.File == "" && l.Severity == "" {
+		return json.Marshal(l.Flag)
+	}
+	return json.Marshal(levelConfigJSON(l))
+}
+
+// UnmarshalJSON reads either a flag string or an object with a flag, a
+// file and a severity.
+func (l *LevelConfig) UnmarshalJSON(data []byte) error {
+	var flag string
+	if err := json.Unmarshal(data, &flag); err == nil {
+		*l = LevelConfig{Flag: flag}
+		return nil
+	}
+	var level levelConfigJSON
+	if err := json.Unmarshal(data, &level); err != nil {
+		return fmt.Errorf("log level must be a flag or an object with a flag, file and severity: %w", err)
+	}
+	*l = LevelConfig(level)
+	return nil
+}
+
+// levelsFromFlags builds levels without files from a name to flag map.
+func levelsFromFlags(flags map[string]string) map[string]LevelConfig {
+	if flags == nil {
+		return nil
+	}
+	levels := make(map[string]LevelConfig, len(flags))
+	for name, flag := range flags {
+		levels[name] = LevelConfig{Flag: flag}
+	}
+	return levels
+}
+
+// level returns the configured level called name, ignoring case when
+// there is no exact match.
+func (c *Config) level(name string) (LevelConfig, bool) {
+	if level, ok := c.LogLevels[name]; ok {
+		return level, true
+	}
+	for configured, level := range c.LogLevels {
+		if strings.EqualFold(configured, name) {
+			return level, true
+		}
+	}
+	return LevelConfig{}, false
+}
+
+// levelFile returns the file entries at level are routed to, or "" when
+// they go to the main log file.
+func (c *Config) levelFile(level string) string {
+	configured, _ := c.level(level)
+	return configured.File
+}
+
+// severity returns the syslog severity of entries at level: the level's
+// configured severity, or the standard one for its name. It reports false
+// when the level has neither.
+func (c *Config) severity(level string) (int, bool) {
+	if configured, ok := c.level(level); ok && configured.Severity != "" {
+		if severity, err := parseSeverity(configured.Severity); err == nil {
+			return severity, true
+		}
+	}
+	severity, ok := levelSeverities[strings.ToLower(level)]
+	return severity, ok
+}
+
+// parseSeverity parses a syslog severity given by name, such as "notice",
+// or as a number from 0 (emergency) to 7 (debug).
+func parseSeverity(value string) (int, error) {
+	if severity, ok := levelSeverities[strings.ToLower(value)]; ok {
+		return severity, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 7 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid severity %q: use a number from 0 to 7 or one of %s", value, strings.Join(severityNames(), ", "))
+}
+
+// parseSeverities parses 'level:severity,...' as given to --severities.
+func parseSeverities(value string) (map[string]string, error) {
+	severities := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, severity, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid severity mapping %q: expected level:severity", pair)
+		}
+		severities[strings.TrimSpace(name)] = strings.TrimSpace(severity)
+	}
+	return severities, nil
+}
+
+// parseLevelOrder returns the level names of a --levels value in the
+// order they were given, which is least to most severe.
+func parseLevelOrder(levelsStr string) []string {
+	var order []string
+	for _, pair := range strings.Split(levelsStr, ",") {
+		name, _, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && strings.TrimSpace(name) != "" {
+			order = append(order, strings.TrimSpace(name))
+		}
+	}
+	return order
+}
+
+// levelOrder returns the configured levels from least to most severe: in
+// the order they were given to --levels, or for configs saved without an
+// order, the levels with a known severity ranked by it.
+func (c *Config) levelOrder() []string {
+	if len(c.LevelOrder) > 0 {
+		return c.LevelOrder
+	}
+
+	var order []string
+	for name := range c.LogLevels {
+		if _, ok := c.severity(name); ok {
+			order = append(order, name)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, _ := c.severity(order[i])
+		b, _ := c.severity(order[j])
+		if a != b {
+			return a > b
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
+// levelRank returns the position of level in levelOrder, ignoring case,
+// so a more severe level has a higher rank. It reports false for levels
+// that are not ranked.
+func (c *Config) levelRank(level string) (int, bool) {
+	for i, name := range c.levelOrder() {
+		if strings.EqualFold(name, level) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// displayLevels lists the levels in the form --levels takes, in order
+// when one is given and otherwise by name.
+func displayLevels(levels map[string]LevelConfig, order []string) string {
+	var names []string
+	for _, name := range order {
+		if _, ok := levels[name]; ok {
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range levels {
+		if !slices.Contains(names, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	names = append(names, rest...)
+
+	var pairs []string
+	for _, name := range names {
+		pair := name + ":" + levels[name].Flag
+		if levels[name].File != "" {
+			pair += ":" + levels[name].File
+		}
+		pairs = append(pairs, pair)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// displaySeverities lists the levels with a configured syslog severity.
+func displaySeverities(levels map[string]LevelConfig) string {
+	var pairs []string
+	for name, level := range levels {
+		if level.Severity != "" {
+			pairs = append(pairs, name+":"+level.Severity)
+		}
+	}
+	if len(pairs) == 0 {
+		return "standard"
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
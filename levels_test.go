@@ -0,0 +1,467 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLevelConfig_JSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]LevelConfig
+		output   string
+	}{
+		{
+			name:     "flags from earlier configs",
+			input:    `{"info":"i","warn":"w"}`,
+			expected: map[string]LevelConfig{"info": {Flag: "i"}, "warn": {Flag: "w"}},
+			output:   `{"info":"i","warn":"w"}`,
+		},
+		{
+			name:     "levels with files",
+			input:    `{"error":{"flag":"e","file":"errors.log"},"info":"i"}`,
+			expected: map[string]LevelConfig{"error": {Flag: "e", File: "errors.log"}, "info": {Flag: "i"}},
+			output:   `{"error":{"flag":"e","file":"errors.log"},"info":"i"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var levels map[string]LevelConfig
+			if err := json.Unmarshal([]byte(tt.input), &levels); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(levels, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, levels)
+			}
+
+			data, err := json.Marshal(levels)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(data) != tt.output {
+				t.Errorf("Expected %s, got %s", tt.output, data)
+			}
+		})
+	}
+
+	var levels map[string]LevelConfig
+	if err := json.Unmarshal([]byte(`{"info":3}`), &levels); err == nil {
+		t.Error("Expected error for a numeric level, got nil")
+	}
+}
+
+func TestConfig_SinksFor(t *testing.T) {
+	config := &Config{
+		Sinks: []SinkConfig{
+			{Path: "team.log", Format: FormatJSONL, MinLevel: "info"},
+			{Path: "alerts.log", MinLevel: "error"},
+		},
+		LogLevels: map[string]LevelConfig{
+			"debug": {Flag: "d", File: "debug.log"},
+			"info":  {Flag: "i"},
+			"error": {Flag: "e", File: "errors.log"},
+		},
+	}
+
+	tests := []struct {
+		level    string
+		expected []SinkConfig
+	}{
+		{level: "info", expected: []SinkConfig{config.Sinks[0]}},
+		{
+			level:    "error",
+			expected: []SinkConfig{{Path: "errors.log", Format: FormatJSONL}, config.Sinks[1]},
+		},
+		{level: "debug", expected: []SinkConfig{{Path: "debug.log", Format: FormatJSONL}}},
+		{level: "warn", expected: []SinkConfig{config.Sinks[0]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			if got := config.sinksFor(tt.level); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	var files []string
+	for _, file := range config.logFiles() {
+		files = append(files, file.LogFile)
+	}
+	expected := []string{"team.log", "alerts.log", "debug.log", "errors.log"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected log files %v, got %v", expected, files)
+	}
+}
+
+func TestLogService_LevelRouting(t *testing.T) {
+	config := Config{
+		Sinks: []SinkConfig{{Path: "/logs/app.log"}},
+		LogLevels: map[string]LevelConfig{
+			"debug": {Flag: "d", File: "/logs/debug.log"},
+			"info":  {Flag: "i"},
+			"error": {Flag: "e", File: "/logs/errors.log"},
+		},
+		FileOrder: FileOrderOldestFirst,
+	}
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	for _, level := range []string{"error", "debug", "info"} {
+		if err := logService.AppendLog(level, level+" message"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		"/logs/errors.log": "ERROR: error message",
+		"/logs/debug.log":  "DEBUG: debug message",
+		"/logs/app.log":    "INFO: info message",
+	}
+	for path, want := range expected {
+		content := string(mockFS.readFiles[path])
+		if !strings.Contains(content, want) || strings.Count(content, "\n") != 1 {
+			t.Errorf("Expected %s to hold only %q, got %q", path, want, content)
+		}
+	}
+	if !mockPrinter.ContainsMessage("Logged to /logs/errors.log") {
+		t.Errorf("Expected output naming the level's file, got %v", mockPrinter.GetMessages())
+	}
+}
+
+func TestLogService_ViewLog_RoutedLevels(t *testing.T) {
+	levels := map[string]LevelConfig{
+		"debug": {Flag: "d", File: "/logs/debug.log"},
+		"info":  {Flag: "i"},
+		"error": {Flag: "e", File: "/logs/errors.log"},
+	}
+
+	tests := []struct {
+		name           string
+		writeMode      string
+		missing        string
+		options        ViewOptions
+		expectedOutput string
+		expectedHeader string
+	}{
+		{
+			name: "level files are merged by time",
+			expectedOutput: "[2024-01-15 10:30:00] DEBUG: cache warm\n" +
+				"[2024-01-15 10:31:00] INFO: started\n" +
+				"[2024-01-15 10:32:00] ERROR: backup failed\n  disk full\n" +
+				"[2024-01-15 10:33:00] INFO: retrying\n",
+		},
+		{
+			name:           "routed level",
+			options:        ViewOptions{Levels: []string{"error"}},
+			expectedOutput: "[2024-01-15 10:32:00] ERROR: backup failed\n  disk full\n",
+		},
+		{
+			name:      "prepend mode shows newest first",
+			writeMode: "prepend",
+			options:   ViewOptions{Since: "2024-01-15 10:31"},
+			expectedOutput: "[2024-01-15 10:33:00] INFO: retrying\n" +
+				"[2024-01-15 10:32:00] ERROR: backup failed\n  disk full\n" +
+				"[2024-01-15 10:31:00] INFO: started\n",
+		},
+		{
+			name:    "level file not written yet",
+			missing: "/logs/errors.log",
+			expectedOutput: "[2024-01-15 10:30:00] DEBUG: cache warm\n" +
+				"[2024-01-15 10:31:00] INFO: started\n" +
+				"[2024-01-15 10:33:00] INFO: retrying\n",
+			expectedHeader: "/logs/app.log, /logs/debug.log",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				Sinks:     []SinkConfig{{Path: "/logs/app.log"}},
+				LogLevels: levels,
+				WriteMode: tt.writeMode,
+				FileOrder: FileOrderOldestFirst,
+			}
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readFiles["/logs/app.log"] = []byte("[2024-01-15 10:31:00] INFO: started\n[2024-01-15 10:33:00] INFO: retrying\n")
+			mockFS.readFiles["/logs/debug.log"] = []byte("[2024-01-15 10:30:00] DEBUG: cache warm\n")
+			mockFS.readFiles["/logs/errors.log"] = []byte("[2024-01-15 10:32:00] ERROR: backup failed\n  disk full\n")
+			delete(mockFS.readFiles, tt.missing)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+			if err := logService.ViewLog(tt.options); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Contains(mockPrinter.GetMessages(), tt.expectedOutput) {
+				t.Errorf("Expected output %q, got %v", tt.expectedOutput, mockPrinter.GetMessages())
+			}
+			header := tt.expectedHeader
+			if header == "" {
+				header = "/logs/app.log, /logs/debug.log, /logs/errors.log"
+			}
+			if !slices.Contains(mockPrinter.GetMessages(), Bold+"Log file contents: "+Reset+header) {
+				t.Errorf("Expected the header to name every file shown, got %v", mockPrinter.GetMessages())
+			}
+		})
+	}
+}
+
+func TestParseLevelOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "debug:d,info:i,warn:w,error:e", expected: []string{"debug", "info", "warn", "error"}},
+		{input: " trace:t , fatal:f:./fatal.log", expected: []string{"trace", "fatal"}},
+		{input: "info:i,broken", expected: []string{"info"}},
+		{input: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := parseLevelOrder(tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConfig_LevelRank(t *testing.T) {
+	ordered := &Config{
+		LogLevels:  map[string]LevelConfig{"debug": {Flag: "d"}, "info": {Flag: "i"}, "success": {Flag: "s"}, "error": {Flag: "e"}},
+		LevelOrder: []string{"debug", "info", "success", "error"},
+	}
+	unordered := &Config{
+		LogLevels: map[string]LevelConfig{"debug": {Flag: "d"}, "warn": {Flag: "w"}, "audit": {Flag: "a"}, "success": {Flag: "s", Severity: "notice"}, "error": {Flag: "e"}},
+	}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		level    string
+		expected int
+		ranked   bool
+	}{
+		{name: "given order", config: ordered, level: "success", expected: 2, ranked: true},
+		{name: "ignores case", config: ordered, level: "ERROR", expected: 3, ranked: true},
+		{name: "not in the order", config: ordered, level: "warn"},
+		{name: "by severity without an order", config: unordered, level: "debug", expected: 0, ranked: true},
+		{name: "custom severity without an order", config: unordered, level: "success", expected: 1, ranked: true},
+		{name: "most severe without an order", config: unordered, level: "error", expected: 3, ranked: true},
+		{name: "no severity without an order", config: unordered, level: "audit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank, ok := tt.config.levelRank(tt.level)
+			if ok != tt.ranked || (ok && rank != tt.expected) {
+				t.Errorf("Expected rank %d (%v), got %d (%v)", tt.expected, tt.ranked, rank, ok)
+			}
+		})
+	}
+}
+
+func TestConfigService_LevelOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		update   ConfigUpdate
+		expected []string
+		errorMsg string
+		display  string
+	}{
+		{
+			name:     "order from --levels",
+			update:   ConfigUpdate{LogLevels: parseLevels("trace:t,info:i,error:e,fatal:f"), LevelOrder: parseLevelOrder("trace:t,info:i,error:e,fatal:f")},
+			expected: []string{"trace", "info", "error", "fatal"},
+			display:  "trace:t,info:i,error:e,fatal:f",
+		},
+		{
+			name:     "levels without an order keep the stored one",
+			update:   ConfigUpdate{LogLevels: map[string]LevelConfig{"info": {Flag: "i"}, "error": {Flag: "e"}, "audit": {Flag: "a"}}},
+			expected: []string{"info", "error"},
+			display:  "info:i,error:e,audit:a",
+		},
+		{
+			name:     "unknown level in the order",
+			update:   ConfigUpdate{LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, LevelOrder: []string{"info", "warn"}},
+			errorMsg: "not a configured level",
+		},
+		{
+			name:     "level given twice",
+			update:   ConfigUpdate{LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, LevelOrder: []string{"info", "info"}},
+			errorMsg: "given twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			mockPrinter := &MockPrinter{}
+			configService := NewConfigService(mockFS, mockPrinter)
+
+			err := configService.UpdateConfig(tt.update)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			config, err := configService.LoadConfig()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(config.LevelOrder, tt.expected) {
+				t.Errorf("Expected order %v, got %v", tt.expected, config.LevelOrder)
+			}
+			if !mockPrinter.ContainsMessage(tt.display) {
+				t.Errorf("Expected output containing %q, got %v", tt.display, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
+
+func TestConfigService_UpdateConfig_StoresLevelOrder(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configService := NewConfigService(mockFS, &MockPrinter{})
+
+	levels := "debug:d,info:i,warn:w,error:e"
+	update := ConfigUpdate{LogFile: "./app.log", LogLevels: parseLevels(levels), LevelOrder: parseLevelOrder(levels), DefaultLevel: "info"}
+	if err := configService.UpdateConfig(update); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var stored struct {
+		LevelOrder []string `json:"level_order"`
+	}
+	if err := json.Unmarshal(mockFS.writeFiles["/tmp/.slog/config.json"], &stored); err != nil {
+		t.Fatalf("Expected the config to be written, got %v", err)
+	}
+	expected := []string{"debug", "info", "warn", "error"}
+	if !reflect.DeepEqual(stored.LevelOrder, expected) {
+		t.Errorf("Expected the level order %v to be stored, got %v", expected, stored.LevelOrder)
+	}
+}
+
+func TestConfigService_SaveConfig_KeepsLevelOrder(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	mockFS.readFiles["/tmp/.slog/config.json"] = []byte(`{"log_file":"./app.log","log_levels":{"debug":"d","info":"i","warn":"w","error":"e"},"level_order":["debug","info","warn","error"]}`)
+	configService := NewConfigService(mockFS, &MockPrinter{})
+
+	levels := map[string]string{"info": "i", "warn": "w", "error": "e", "audit": "a"}
+	if err := configService.SaveConfig("./app.log", levels, "info", ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config, err := configService.LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []string{"info", "warn", "error"}
+	if !reflect.DeepEqual(config.LevelOrder, expected) {
+		t.Errorf("Expected the stored order of the remaining levels %v, got %v", expected, config.LevelOrder)
+	}
+}
+
+func TestLogService_ViewLog_Levels(t *testing.T) {
+	const log = "[2024-01-15 10:30:00] DEBUG: cache warm\n" +
+		"[2024-01-15 10:31:00] INFO: started\n" +
+		"[2024-01-15 10:32:00] WARN: disk at 85%\n" +
+		"[2024-01-15 10:33:00] ERROR: backup failed\n" +
+		"  disk full\n" +
+		"[2024-01-15 10:34:00] AUDIT: login\n"
+
+	levels := map[string]LevelConfig{"debug": {Flag: "d"}, "info": {Flag: "i"}, "warn": {Flag: "w"}, "error": {Flag: "e"}, "audit": {Flag: "a"}}
+
+	tests := []struct {
+		name           string
+		order          []string
+		options        ViewOptions
+		expectedOutput string
+		errorMsg       string
+	}{
+		{
+			name:           "one level",
+			options:        ViewOptions{Levels: []string{"error"}},
+			expectedOutput: "[2024-01-15 10:33:00] ERROR: backup failed\n  disk full\n",
+		},
+		{
+			name:           "several levels",
+			options:        ViewOptions{Levels: []string{"WARN", "error"}},
+			expectedOutput: "[2024-01-15 10:32:00] WARN: disk at 85%\n[2024-01-15 10:33:00] ERROR: backup failed\n  disk full\n",
+		},
+		{
+			name:           "minimum level from the order",
+			order:          []string{"debug", "info", "audit", "warn", "error"},
+			options:        ViewOptions{MinLevel: "audit"},
+			expectedOutput: "[2024-01-15 10:32:00] WARN: disk at 85%\n[2024-01-15 10:33:00] ERROR: backup failed\n  disk full\n[2024-01-15 10:34:00] AUDIT: login\n",
+		},
+		{
+			name:           "minimum level by severity without an order",
+			options:        ViewOptions{MinLevel: "warn"},
+			expectedOutput: "[2024-01-15 10:32:00] WARN: disk at 85%\n[2024-01-15 10:33:00] ERROR: backup failed\n  disk full\n",
+		},
+		{
+			name:           "minimum level and levels together",
+			order:          []string{"debug", "info", "warn", "error"},
+			options:        ViewOptions{MinLevel: "info", Levels: []string{"debug", "info"}},
+			expectedOutput: "[2024-01-15 10:31:00] INFO: started\n",
+		},
+		{
+			name:     "unknown minimum level",
+			order:    []string{"debug", "info", "warn", "error"},
+			options:  ViewOptions{MinLevel: "audit"},
+			errorMsg: "use one of debug, info, warn, error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			config := Config{LogFile: "/tmp/test.log", LogLevels: levels, LevelOrder: tt.order, FileOrder: FileOrderOldestFirst}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readFiles["/tmp/test.log"] = []byte(log)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+			err := logService.ViewLog(tt.options)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Contains(mockPrinter.GetMessages(), tt.expectedOutput) {
+				t.Errorf("Expected output %q, got %v", tt.expectedOutput, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultLockTimeout is how long a writer waits for the log file lock when
+// the config does not set one.
+const DefaultLockTimeout = 10 * time.Second
+
+const lockPollInterval = 20 * time.Millisecond
+
+// ErrLockTimeout is returned when the log file lock could not be acquired
+// before the timeout expired.
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// lockPath returns the path of the lock file guarding a log file. A
+// separate file is used so the lock survives the log file being replaced.
+func lockPath(logFile string) string {
+	return logFile + ".lock"
+}
+
+// parseLockTimeout parses the configured lock timeout, falling back to
+// DefaultLockTimeout when it is empty.
+func parseLockTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultLockTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lock timeout %q: %w", value, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid lock timeout %q: must not be negative", value)
+	}
+	return timeout, nil
+}
+
+// lockLogFile takes the lock guarding the config's log file, waiting up to
+// the configured lock timeout.
+func lockLogFile(fs FileSystem, config *Config) (io.Closer, error) {
+	timeout, err := parseLockTimeout(config.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := fs.Lock(lockPath(config.LogFile), timeout)
+	if err != nil {
+		if errors.Is(err, ErrLockTimeout) {
+			return nil, fmt.Errorf("could not lock %s: %w; another slog process may be writing to it", config.LogFile, err)
+		}
+		return nil, fmt.Errorf("error locking log file: %w", err)
+	}
+	return lock, nil
+}
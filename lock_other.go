@@ -0,0 +1,44 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// exclusiveLockFile is held for as long as the lock file exists. It is used
+// where flock(2) is not available.
+type exclusiveLockFile struct {
+	path string
+}
+
+func (l *exclusiveLockFile) Close() error {
+	return os.Remove(l.path)
+}
+
+// lockFile creates path exclusively, polling until the timeout expires. A
+// lock file left behind by a killed process must be removed by hand.
+func lockFile(path string, timeout time.Duration) (io.Closer, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			if err := file.Close(); err != nil {
+				_ = os.Remove(path)
+				return nil, err
+			}
+			return &exclusiveLockFile{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w after %s (remove %s if no slog process is running)", ErrLockTimeout, timeout, path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFile_Contention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.lock")
+
+	first, err := lockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = lockFile(path, 50*time.Millisecond)
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Expected ErrLockTimeout while the lock is held, got %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Expected no error releasing lock, got %v", err)
+	}
+
+	second, err := lockFile(path, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected lock to be free after release, got %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatalf("Expected no error releasing lock, got %v", err)
+	}
+}
+
+func TestLockFile_WaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.lock")
+
+	held, err := lockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = held.Close()
+	}()
+
+	lock, err := lockFile(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected lock once released, got %v", err)
+	}
+	if err := lock.Close(); err != nil {
+		t.Fatalf("Expected no error releasing lock, got %v", err)
+	}
+}
+
+// Test that concurrent prepend writers do not lose entries
+func TestLogService_ConcurrentWriters(t *testing.T) {
+	fs := NewTempHomeFileSystem(t)
+	logFile := filepath.Join(fs.home, "shared.log")
+
+	config := Config{LogFile: logFile, WriteMode: "prepend", LockTimeout: "30s"}
+	configJSON, _ := json.Marshal(config)
+	if err := os.MkdirAll(filepath.Join(fs.home, ".slog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fs.home, ".slog", "config.json"), configJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Each writer gets its own services, as separate processes would.
+			configService := NewConfigService(fs, &MockPrinter{})
+			logService := NewLogService(configService, fs, &MockPrinter{})
+			errs <- logService.AppendLog("info", fmt.Sprintf("writer %d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < writers; i++ {
+		if !strings.Contains(string(data), fmt.Sprintf("writer %d\n", i)) {
+			t.Errorf("Expected entry from writer %d in %q", i, data)
+		}
+	}
+}
+
+func TestParseLockTimeout(t *testing.T) {
+	timeout, err := parseLockTimeout("")
+	if err != nil || timeout != DefaultLockTimeout {
+		t.Errorf("Expected default timeout %s, got %s (%v)", DefaultLockTimeout, timeout, err)
+	}
+
+	timeout, err = parseLockTimeout("250ms")
+	if err != nil || timeout != 250*time.Millisecond {
+		t.Errorf("Expected 250ms, got %s (%v)", timeout, err)
+	}
+}
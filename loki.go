@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lokiPushPath is appended to a Loki sink URL without a path.
+const lokiPushPath = "/loki/api/v1/push"
+
+// validateLokiSink checks the settings of a Loki sink.
+func validateLokiSink(s SinkConfig) error {
+	if err := validateHTTPSink(s); err != nil {
+		return err
+	}
+	for name := range s.Labels {
+		if !validLokiLabel(name) {
+			return fmt.Errorf("invalid label name %q: use letters, digits and underscores, not starting with a digit", name)
+		}
+		if name == "level" {
+			return fmt.Errorf("label %q is set from each entry's level", name)
+		}
+	}
+	return nil
+}
+
+// validLokiLabel reports whether name is a valid Prometheus label name,
+// which Loki requires.
+func validLokiLabel(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// lokiEndpoint returns the URL entries are pushed to: the sink's URL,
+// with /loki/api/v1/push added when it has no path.
+func lokiEndpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Path != "" && u.Path != "/") {
+		return rawURL
+	}
+	u.Path = lokiPushPath
+	return u.String()
+}
+
+// lokiLine renders an entry as a Loki log line: the message, then the
+// host and fields as logfmt pairs, which LogQL's logfmt parser extracts.
+func lokiLine(entry Entry) string {
+	pairs := fieldPairs(entry.Fields)
+	if _, ok := entry.Fields["host"]; !ok && entry.Host != "" {
+		pairs = append([]logfmtPair{{Key: "host", Value: entry.Host}}, pairs...)
+	}
+	if len(pairs) == 0 {
+		return entry.Message
+	}
+	return entry.Message + " " + encodeLogfmt(pairs)
+}
+
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiEncoder returns the encoder of a Loki sink. Each level is one
+// stream, labelled with the level in lower case and the sink's labels;
+// timestamps are nanoseconds since the Unix epoch.
+func lokiEncoder(sink SinkConfig) httpEncoder {
+	return func(entries []Entry) ([]byte, string, error) {
+		var request lokiPushRequest
+		streams := make(map[string]int)
+		for _, entry := range entries {
+			level := strings.ToLower(entry.Level)
+			i, ok := streams[level]
+			if !ok {
+				labels := map[string]string{"level": level}
+				for name, value := range sink.Labels {
+					labels[name] = value
+				}
+				i = len(request.Streams)
+				streams[level] = i
+				request.Streams = append(request.Streams, lokiStream{Stream: labels})
+			}
+			request.Streams[i].Values = append(request.Streams[i].Values, [2]string{
+				strconv.FormatInt(entry.Time.UnixNano(), 10),
+				lokiLine(entry),
+			})
+		}
+		body, err := json.Marshal(request)
+		return body, "application/json", err
+	}
+}
+
+// displayLabels lists a Loki sink's static labels.
+func displayLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// newLokiSink prepares a sink that pushes gzipped batches of entries to
+// Loki.
+func newLokiSink(fs FileSystem, sink SinkConfig) (*httpSink, error) {
+	endpoint := sink
+	endpoint.URL = lokiEndpoint(sink.URL)
+	return newHTTPSink(fs, endpoint, "loki "+endpoint.URL, lokiEncoder(sink), true)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLokiLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    Entry
+		expected string
+	}{
+		{name: "message only", entry: Entry{Message: "Backup done"}, expected: "Backup done"},
+		{name: "host", entry: Entry{Message: "Backup done", Host: "db-1"}, expected: "Backup done host=db-1"},
+		{
+			name:     "fields",
+			entry:    Entry{Message: "Backup failed", Host: "db-1", Fields: map[string]string{"volume": "data", "error": "disk full"}},
+			expected: `Backup failed host=db-1 error="disk full" volume=data`,
+		},
+		{
+			name:     "host field wins",
+			entry:    Entry{Message: "Backup done", Host: "db-1", Fields: map[string]string{"host": "db-2"}},
+			expected: "Backup done host=db-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lokiLine(tt.entry); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLokiEndpoint(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "http://loki:3100", expected: "http://loki:3100/loki/api/v1/push"},
+		{url: "https://logs.example.com/", expected: "https://logs.example.com/loki/api/v1/push"},
+		{url: "https://logs.example.com/api/prom/push", expected: "https://logs.example.com/api/prom/push"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := lokiEndpoint(tt.url); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLokiEncoder(t *testing.T) {
+	stamp := time.Unix(1705314600, 123456789)
+	entries := []Entry{
+		{Time: stamp, Level: "ERROR", Message: "Backup failed"},
+		{Time: stamp.Add(time.Second), Level: "INFO", Message: "Retrying"},
+		{Time: stamp.Add(2 * time.Second), Level: "ERROR", Message: "Backup failed again"},
+	}
+
+	encode := lokiEncoder(SinkConfig{Type: SinkLoki, Labels: map[string]string{"job": "backup"}})
+	body, contentType, err := encode(entries)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Expected application/json, got %q", contentType)
+	}
+	expected := `{"streams":[` +
+		`{"stream":{"job":"backup","level":"error"},"values":[["1705314600123456789","Backup failed"],["1705314602123456789","Backup failed again"]]},` +
+		`{"stream":{"job":"backup","level":"info"},"values":[["1705314601123456789","Retrying"]]}]}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+func TestLogService_LokiSink(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []lokiPushRequest
+	var paths, encodings, tenants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Expected a gzipped body, got %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var push lokiPushRequest
+		if err := json.NewDecoder(reader).Decode(&push); err != nil {
+			t.Errorf("Expected a push request, got %v", err)
+		}
+		pushes = append(pushes, push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := Config{
+		Sinks: []SinkConfig{
+			{Path: "/logs/app.log"},
+			{Type: SinkLoki, URL: server.URL, Labels: map[string]string{"job": "backup"}, Headers: map[string]string{"X-Scope-OrgID": "ops"}, BatchSize: 2},
+		},
+		FileOrder: FileOrderOldestFirst,
+	}
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	before := time.Now()
+	input := strings.NewReader("copying\nverifying\ndone\n")
+	if err := logService.AppendFromReader("warn", input, map[string]string{"volume": "data"}, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.Close(); err != nil {
+		t.Fatalf("Expected no error closing sinks, got %v", err)
+	}
+
+	if len(pushes) != 2 {
+		t.Fatalf("Expected 2 pushes of at most 2 entries, got %d", len(pushes))
+	}
+	for i := range pushes {
+		if paths[i] != "/loki/api/v1/push" || encodings[i] != "gzip" || tenants[i] != "ops" {
+			t.Errorf("Expected a gzipped push to /loki/api/v1/push with the tenant header, got %s %q %q", paths[i], encodings[i], tenants[i])
+		}
+	}
+
+	stream := pushes[0].Streams[0]
+	if stream.Stream["level"] != "warn" || stream.Stream["job"] != "backup" || len(stream.Stream) != 2 {
+		t.Errorf("Expected the level and static labels, got %v", stream.Stream)
+	}
+	var lines []string
+	for _, push := range pushes {
+		for _, value := range push.Streams[0].Values {
+			lines = append(lines, value[1])
+			if stamp, err := strconv.ParseInt(value[0], 10, 64); err != nil || stamp < before.UnixNano() {
+				t.Errorf("Expected a nanosecond timestamp after %d, got %s", before.UnixNano(), value[0])
+			}
+		}
+	}
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "copying ") || !strings.HasSuffix(lines[2], " volume=data") {
+		t.Errorf("Expected the three lines with their fields, got %q", lines)
+	}
+	if !mockPrinter.ContainsMessage("Logged 3 entries to /logs/app.log, loki " + server.URL + "/loki/api/v1/push") {
+		t.Errorf("Expected output naming both sinks, got %v", mockPrinter.GetMessages())
+	}
+}
+
+func TestConfigService_LokiSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		sink     SinkConfig
+		errorMsg string
+	}{
+		{name: "url only", sink: SinkConfig{Type: SinkLoki, URL: "http://loki:3100"}},
+		{name: "labels", sink: SinkConfig{Type: SinkLoki, URL: "http://loki:3100", Labels: map[string]string{"job": "cron", "env_2": "prod"}}},
+		{name: "invalid label", sink: SinkConfig{Type: SinkLoki, URL: "http://loki:3100", Labels: map[string]string{"2fa": "x"}}, errorMsg: "invalid label name"},
+		{name: "dashed label", sink: SinkConfig{Type: SinkLoki, URL: "http://loki:3100", Labels: map[string]string{"app-name": "x"}}, errorMsg: "invalid label name"},
+		{name: "level label", sink: SinkConfig{Type: SinkLoki, URL: "http://loki:3100", Labels: map[string]string{"level": "x"}}, errorMsg: "set from each entry's level"},
+		{name: "missing url", sink: SinkConfig{Type: SinkLoki}, errorMsg: "requires a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configService := NewConfigService(mockFS, &MockPrinter{})
+
+			err := configService.UpdateConfig(ConfigUpdate{AddSinks: []SinkConfig{tt.sink}})
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
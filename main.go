@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 )
@@ -30,10 +36,64 @@ const (
 )
 
 type Config struct {
-	LogFile      string            `json:"log_file"`
-	LogLevels    map[string]string `json:"log_levels"`
-	DefaultLevel string            `json:"default_level"`
-	WriteMode    string            `json:"write_mode"`
+	// LogFile is the file a file sink writes to. A stored config only sets
+	// it if it was written before sinks existed; LoadConfig moves it into
+	// Sinks.
+	LogFile       string                 `json:"log_file,omitempty"`
+	Sinks         []SinkConfig           `json:"sinks"`
+	LogLevels     map[string]LevelConfig `json:"log_levels"`
+	LevelOrder    []string               `json:"level_order,omitempty"`
+	DefaultLevel  string                 `json:"default_level"`
+	WriteMode     string                 `json:"write_mode"`
+	Format        string                 `json:"format"`
+	Template      string                 `json:"template,omitempty"`
+	TimeFormat    string                 `json:"time_format,omitempty"`
+	TimeZone      string                 `json:"time_zone,omitempty"`
+	LockTimeout   string                 `json:"lock_timeout,omitempty"`
+	MaxSize       string                 `json:"max_size,omitempty"`
+	MaxBackups    int                    `json:"max_backups,omitempty"`
+	Rotate        string                 `json:"rotate,omitempty"`
+	Compress      bool                   `json:"compress,omitempty"`
+	CompressAfter string                 `json:"compress_after,omitempty"`
+	RetainAge     string                 `json:"retain_age,omitempty"`
+	RetainSize    string                 `json:"retain_size,omitempty"`
+	RetainEntries int                    `json:"retain_entries,omitempty"`
+	FileOrder     string                 `json:"file_order,omitempty"`
+}
+
+// FormatOptions returns the settings the formatter needs from the config.
+func (c *Config) FormatOptions() FormatOptions {
+	return FormatOptions{
+		Template:   c.Template,
+		TimeFormat: c.TimeFormat,
+		TimeZone:   c.TimeZone,
+	}
+}
+
+// ConfigUpdate holds the settings passed to 'slog config'.
+// Empty fields keep their existing value.
+type ConfigUpdate struct {
+	LogFile       string
+	LogLevels     map[string]LevelConfig
+	LevelOrder    []string
+	DefaultLevel  string
+	WriteMode     string
+	Format        string
+	Template      string
+	TimeFormat    string
+	TimeZone      string
+	LockTimeout   string
+	MaxSize       string
+	MaxBackups    int
+	Rotate        string
+	Compress      *bool
+	CompressAfter string
+	RetainAge     string
+	RetainSize    string
+	RetainEntries int
+	AddSinks      []SinkConfig
+	RemoveSinks   []string
+	Severities    map[string]string
 }
 
 type FileSystem interface {
@@ -41,7 +101,30 @@ type FileSystem interface {
 	MkdirAll(path string, perm os.FileMode) error
 	WriteFile(filename string, data []byte, perm os.FileMode) error
 	ReadFile(filename string) ([]byte, error)
-	OpenFile(name string, flag int, perm os.FileMode) (*os.File, error)
+	Open(name string) (ReadableFile, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Lock(path string, timeout time.Duration) (io.Closer, error)
+	CreateTemp(dir, pattern string) (File, error)
+	Rename(oldpath, newpath string) error
+	Stat(name string) (os.FileInfo, error)
+	Chmod(name string, mode os.FileMode) error
+	Remove(name string) error
+	ReadDir(name string) ([]os.DirEntry, error)
+}
+
+// File is the part of *os.File used when writing a file in place of another.
+type File interface {
+	io.Writer
+	Sync() error
+	Close() error
+	Name() string
+}
+
+// ReadableFile is the part of *os.File used when reading parts of a file.
+type ReadableFile interface {
+	io.ReaderAt
+	io.Closer
+	Stat() (os.FileInfo, error)
 }
 
 type Printer interface {
@@ -69,10 +152,42 @@ func (fs *RealFileSystem) ReadFile(filename string) ([]byte, error) {
 	return os.ReadFile(filename)
 }
 
-func (fs *RealFileSystem) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+func (fs *RealFileSystem) Open(name string) (ReadableFile, error) {
+	return os.Open(name)
+}
+
+func (fs *RealFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
 	return os.OpenFile(name, flag, perm)
 }
 
+func (fs *RealFileSystem) Lock(path string, timeout time.Duration) (io.Closer, error) {
+	return lockFile(path, timeout)
+}
+
+func (fs *RealFileSystem) CreateTemp(dir, pattern string) (File, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+func (fs *RealFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (fs *RealFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (fs *RealFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (fs *RealFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (fs *RealFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
 type ConsolePrinter struct{}
 
 func (p *ConsolePrinter) Print(msg string) {
@@ -101,47 +216,233 @@ func NewConfigService(fs FileSystem, printer Printer) *ConfigService {
 }
 
 func (cs *ConfigService) SaveConfig(logFile string, logLevels map[string]string, defaultLevel string, writeMode string) error {
+	return cs.UpdateConfig(ConfigUpdate{
+		LogFile:      logFile,
+		LogLevels:    levelsFromFlags(logLevels),
+		DefaultLevel: defaultLevel,
+		WriteMode:    writeMode,
+	})
+}
+
+func (cs *ConfigService) UpdateConfig(update ConfigUpdate) error {
 	existingConfig, _ := cs.LoadConfig()
+	if existingConfig != nil && existingConfig.newestFirstOnDisk() {
+		if err := cs.MigratePrependLog(); err != nil {
+			return err
+		}
+		existingConfig.FileOrder = FileOrderOldestFirst
+	}
 
 	config := Config{
-		LogFile: "./log.txt",
-		LogLevels: map[string]string{
-			"debug": "d",
-			"info":  "i",
-			"warn":  "w",
-			"error": "e",
+		Sinks: []SinkConfig{{Path: "./log.txt"}},
+		LogLevels: map[string]LevelConfig{
+			"debug": {Flag: "d"},
+			"info":  {Flag: "i"},
+			"warn":  {Flag: "w"},
+			"error": {Flag: "e"},
 		},
+		LevelOrder:   []string{"debug", "info", "warn", "error"},
 		DefaultLevel: "info",
 		WriteMode:    "append",
+		Format:       FormatText,
 	}
 
 	if existingConfig != nil {
 		config = *existingConfig
 	}
 
-	if logFile != "" {
-		config.LogFile = logFile
+	if update.LogFile != "" {
+		config.setMainLogFile(update.LogFile)
+	}
+
+	for _, target := range update.RemoveSinks {
+		if err := config.removeSink(target); err != nil {
+			return err
+		}
+	}
+	for _, sink := range update.AddSinks {
+		config.addSink(sink)
+	}
+
+	if len(update.LogLevels) > 0 {
+		// Levels that are given again keep their severity
+		for name, level := range update.LogLevels {
+			if existing, ok := config.LogLevels[name]; ok && level.Severity == "" {
+				level.Severity = existing.Severity
+				update.LogLevels[name] = level
+			}
+		}
+		config.LogLevels = update.LogLevels
+		order := update.LevelOrder
+		if len(order) == 0 {
+			// Levels given without an order, as SaveConfig gives them,
+			// keep the stored order of those still configured
+			for _, name := range config.LevelOrder {
+				if _, ok := config.LogLevels[name]; ok {
+					order = append(order, name)
+				}
+			}
+		}
+		config.LevelOrder = nil
+		for _, name := range order {
+			if _, ok := config.LogLevels[name]; !ok {
+				return fmt.Errorf("level order names %q, which is not a configured level", name)
+			}
+			if slices.Contains(config.LevelOrder, name) {
+				return fmt.Errorf("level %q is given twice", name)
+			}
+			config.LevelOrder = append(config.LevelOrder, name)
+		}
 	}
 
-	if len(logLevels) > 0 {
-		config.LogLevels = logLevels
+	for name, severity := range update.Severities {
+		level, ok := config.LogLevels[name]
+		if !ok {
+			return fmt.Errorf("unknown level %q: add it with --levels before setting its severity", name)
+		}
+		if _, err := parseSeverity(severity); err != nil {
+			return err
+		}
+		level.Severity = severity
+		config.LogLevels[name] = level
 	}
 
-	if defaultLevel != "" {
-		config.DefaultLevel = defaultLevel
+	if update.DefaultLevel != "" {
+		config.DefaultLevel = update.DefaultLevel
 	}
 
-	if writeMode != "" {
-		if writeMode != "append" && writeMode != "prepend" {
+	if update.WriteMode != "" {
+		if update.WriteMode != "append" && update.WriteMode != "prepend" {
 			return fmt.Errorf("write mode must be 'append' or 'prepend'")
 		}
-		config.WriteMode = writeMode
+		config.WriteMode = update.WriteMode
+	}
+
+	if update.Format != "" {
+		config.Format = update.Format
+	}
+
+	if update.Template != "" {
+		config.Template = update.Template
+	}
+
+	if update.TimeFormat != "" {
+		config.TimeFormat = update.TimeFormat
+	}
+
+	if update.TimeZone != "" {
+		config.TimeZone = update.TimeZone
+	}
+
+	if update.LockTimeout != "" {
+		if _, err := parseLockTimeout(update.LockTimeout); err != nil {
+			return err
+		}
+		config.LockTimeout = update.LockTimeout
+	}
+
+	if update.MaxSize != "" {
+		if _, err := parseSize(update.MaxSize); err != nil {
+			return err
+		}
+		config.MaxSize = update.MaxSize
+	}
+
+	if update.MaxBackups < 0 {
+		return fmt.Errorf("max backups must not be negative")
+	}
+	if update.MaxBackups > 0 {
+		config.MaxBackups = update.MaxBackups
+	}
+
+	if update.Rotate != "" {
+		if _, ok := rotateSuffixes[update.Rotate]; !ok {
+			return fmt.Errorf("rotate must be '%s' or '%s'", RotateDaily, RotateHourly)
+		}
+		config.Rotate = update.Rotate
+	}
+
+	for _, file := range config.logFiles() {
+		if _, err := newPeriodPattern(file); err != nil {
+			return err
+		}
+		// Date tokens in a file name take the place of the rotate option
+		if config.Rotate == "" || !containsDateToken(filepath.Base(file.LogFile)) {
+			continue
+		}
+		if update.Rotate != "" {
+			return fmt.Errorf("rotate cannot be combined with date tokens in the file name %q: use one or the other", file.LogFile)
+		}
+		cs.printer.PrintWarning(fmt.Sprintf("%s has date tokens in its name, so rotate '%s' does not apply to it", file.LogFile, config.Rotate))
+	}
+
+	if update.Compress != nil {
+		config.Compress = *update.Compress
+	}
+
+	if update.CompressAfter != "" {
+		if _, err := parseCompressAfter(update.CompressAfter); err != nil {
+			return err
+		}
+		config.CompressAfter = update.CompressAfter
+	}
+
+	if update.RetainAge != "" {
+		config.RetainAge = update.RetainAge
+	}
+	if update.RetainSize != "" {
+		config.RetainSize = update.RetainSize
+	}
+	if update.RetainEntries != 0 {
+		config.RetainEntries = update.RetainEntries
+	}
+	if _, err := config.retentionPolicy(); err != nil {
+		return err
+	}
+
+	if update.Format != "" || update.Template != "" || update.TimeFormat != "" || update.TimeZone != "" {
+		if err := ValidateFormat(config.Format, config.FormatOptions()); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Sinks) == 0 {
+		return fmt.Errorf("at least one sink is required")
+	}
+	for _, sink := range config.Sinks {
+		if err := config.validateSink(sink); err != nil {
+			return err
+		}
 	}
+	config.FileOrder = FileOrderOldestFirst
 
-	if config.LogFile == "" {
-		return fmt.Errorf("log file path is required")
+	if err := cs.writeConfig(&config); err != nil {
+		return err
 	}
 
+	cs.printer.PrintSuccess("Configuration saved successfully")
+	cs.printer.Print(Bold + "Log File: " + Reset + displayMainLog(&config))
+	cs.printer.Print(Bold + "Sinks: " + Reset + displaySinks(&config))
+	cs.printer.Print(Bold + "Log Levels: " + Reset + displayLevels(config.LogLevels, config.LevelOrder))
+	cs.printer.Print(Bold + "Severities: " + Reset + displaySeverities(config.LogLevels))
+	cs.printer.Print(Bold + "Default Level: " + Reset + config.DefaultLevel)
+	cs.printer.Print(Bold + "Write Mode: " + Reset + config.WriteMode)
+	cs.printer.Print(Bold + "Format: " + Reset + displayFormat(config.Format))
+	cs.printer.Print(Bold + "Template: " + Reset + displayTemplate(config.Template))
+	cs.printer.Print(Bold + "Time Format: " + Reset + displayTimeFormat(config.TimeFormat))
+	cs.printer.Print(Bold + "Time Zone: " + Reset + displayTimeZone(config.TimeZone))
+	cs.printer.Print(Bold + "Lock Timeout: " + Reset + displayLockTimeout(config.LockTimeout))
+	cs.printer.Print(Bold + "Max Size: " + Reset + displayMaxSize(config.MaxSize))
+	cs.printer.Print(Bold + "Max Backups: " + Reset + strconv.Itoa(config.maxBackups()))
+	cs.printer.Print(Bold + "Compression: " + Reset + displayCompress(&config))
+	cs.printer.Print(Bold + "Retention: " + Reset + displayRetention(&config))
+	cs.printer.Print(Bold + "Time Rotation: " + Reset + displayRotate(&config))
+
+	return nil
+}
+
+// writeConfig stores the config in ~/.slog/config.json.
+func (cs *ConfigService) writeConfig(config *Config) error {
 	homeDir, err := cs.fs.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("error getting home directory: %w", err)
@@ -164,12 +465,6 @@ func (cs *ConfigService) SaveConfig(logFile string, logLevels map[string]string,
 		return fmt.Errorf("error writing config file: %w", err)
 	}
 
-	cs.printer.PrintSuccess("Configuration saved successfully")
-	cs.printer.Print(Bold + "Log File: " + Reset + config.LogFile)
-	cs.printer.Print(Bold + "Log Levels: " + Reset + fmt.Sprintf("%v", config.LogLevels))
-	cs.printer.Print(Bold + "Default Level: " + Reset + config.DefaultLevel)
-	cs.printer.Print(Bold + "Write Mode: " + Reset + config.WriteMode)
-
 	return nil
 }
 
@@ -190,6 +485,7 @@ func (cs *ConfigService) LoadConfig() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
+	config.migrateLogFile()
 
 	return &config, nil
 }
@@ -220,10 +516,22 @@ func (cs *ConfigService) ViewConfig() error {
 
 	cs.printer.Print(Bold + Cyan + "Current Configuration:" + Reset)
 	cs.printer.Print(Bold + "Config File: " + Reset + configFile)
-	cs.printer.Print(Bold + "Log File: " + Reset + config.LogFile)
-	cs.printer.Print(Bold + "Log Levels: " + Reset + fmt.Sprintf("%v", config.LogLevels))
+	cs.printer.Print(Bold + "Log File: " + Reset + displayMainLog(config))
+	cs.printer.Print(Bold + "Sinks: " + Reset + displaySinks(config))
+	cs.printer.Print(Bold + "Log Levels: " + Reset + displayLevels(config.LogLevels, config.LevelOrder))
+	cs.printer.Print(Bold + "Severities: " + Reset + displaySeverities(config.LogLevels))
 	cs.printer.Print(Bold + "Default Level: " + Reset + config.DefaultLevel)
 	cs.printer.Print(Bold + "Write Mode: " + Reset + config.WriteMode)
+	cs.printer.Print(Bold + "Format: " + Reset + displayFormat(config.Format))
+	cs.printer.Print(Bold + "Template: " + Reset + displayTemplate(config.Template))
+	cs.printer.Print(Bold + "Time Format: " + Reset + displayTimeFormat(config.TimeFormat))
+	cs.printer.Print(Bold + "Time Zone: " + Reset + displayTimeZone(config.TimeZone))
+	cs.printer.Print(Bold + "Lock Timeout: " + Reset + displayLockTimeout(config.LockTimeout))
+	cs.printer.Print(Bold + "Max Size: " + Reset + displayMaxSize(config.MaxSize))
+	cs.printer.Print(Bold + "Max Backups: " + Reset + strconv.Itoa(config.maxBackups()))
+	cs.printer.Print(Bold + "Compression: " + Reset + displayCompress(config))
+	cs.printer.Print(Bold + "Retention: " + Reset + displayRetention(config))
+	cs.printer.Print(Bold + "Time Rotation: " + Reset + displayRotate(config))
 
 	return nil
 }
@@ -231,24 +539,150 @@ func (cs *ConfigService) ViewConfig() error {
 func (cs *ConfigService) ShowConfigUsage() {
 	cs.printer.Print(Bold + Cyan + "Configuration Usage:" + Reset)
 	cs.printer.Print(Bold + "Set configuration:" + Reset)
-	cs.printer.Print("  slog config --file <path> --levels <level:flag,...> --default <level> --mode <append|prepend>")
-	cs.printer.Print("  slog config -f <path> -l <level:flag,...> -d <level> -m <append|prepend>")
+	cs.printer.Print("  slog config --file <path> --levels <level:flag,...> --default <level> --mode <append|prepend> --format <text|jsonl|logfmt>")
+	cs.printer.Print("  slog config -f <path> -l <level:flag,...> -d <level> -m <append|prepend> -o <text|jsonl|logfmt>")
 	cs.printer.Print("")
 	cs.printer.Print(Bold + "Examples:" + Reset)
 	cs.printer.Print("  slog config --file ./app.log --levels 'info:i,warn:w,error:e' --default info --mode append")
 	cs.printer.Print("  slog config -f ./app.log -l 'debug:d,info:i' -d debug -m prepend")
+	cs.printer.Print("  slog config --format jsonl")
+	cs.printer.Print("  slog config --template '{{.Time}} {{.Host}} [{{.Level}}] {{.Message}}'")
+	cs.printer.Print("  slog config --time-format rfc3339nano --time-zone utc")
+	cs.printer.Print("  slog config --max-size 10MB --max-backups 3")
+	cs.printer.Print("  slog config --file './logs/app-%Y-%m-%d.log'")
+	cs.printer.Print("  slog config --add-sink './errors.log,min_level=error,format=jsonl'")
+	cs.printer.Print("  slog config --levels 'debug:d:./debug.log,info:i,warn:w,error:e:./errors.log'")
+	cs.printer.Print("  slog config --add-sink 'type=syslog,address=udp://logs.internal:514,facility=local0'")
+	cs.printer.Print("  slog config --add-sink 'type=journald,app_name=maintenance'")
+	cs.printer.Print("  slog config --add-sink 'type=webhook,url=https://hooks.example.com/logs,header.Authorization=Bearer abc'")
+	cs.printer.Print("  slog config --add-sink 'type=otlp,url=http://otel-collector:4318,service=backup'")
+	cs.printer.Print("  slog config --add-sink 'type=loki,url=http://loki:3100,label.job=backup'")
 	cs.printer.Print("")
 	cs.printer.Print(Bold + "Flags:" + Reset)
-	cs.printer.Print("  --file, -f      Path to log file")
-	cs.printer.Print("  --levels, -l    Log levels in format 'level:flag,level:flag'")
+	cs.printer.Print("  --file, -f      Path to the main log file, the first file sink")
+	cs.printer.Print("  --levels, -l    Log levels in format 'level:flag,level:flag'; 'level:flag:file' sends a level to its own file")
 	cs.printer.Print("  --default, -d   Default log level when no level flag is provided")
 	cs.printer.Print("  --mode, -m      Write mode: 'append' (default) or 'prepend'")
+	cs.printer.Print("  --format, -o    Entry format: 'text' (default), 'jsonl' or 'logfmt'")
+	cs.printer.Print("  --template, -t  Line layout for the text format using {{.Time}}, {{.Level}}, {{.Message}}, {{.Host}} and {{.Fields}}")
+	cs.printer.Print("  --time-format   Go time layout, or one of rfc3339, rfc3339nano, unix, unixmilli, unixmicro, unixnano")
+	cs.printer.Print("  --time-zone     Time zone: 'local' (default), 'utc' or an IANA name like 'Europe/Berlin'")
+	cs.printer.Print("  --lock-timeout  How long to wait for other slog processes writing the same file (default 10s)")
+	cs.printer.Print("  --max-size      Rotate the log file before it grows past this size, e.g. '10MB'")
+	cs.printer.Print("  --max-backups   Number of rotated files to keep (default 5)")
+	cs.printer.Print("  --rotate        Start a new dated log file 'daily' or 'hourly'; the file path may also contain %Y, %m, %d and %H")
+	cs.printer.Print("  --compress      Gzip rotated log files; use --compress=false to stop")
+	cs.printer.Print("  --compress-after  Only compress rotated files older than this, e.g. '24h'")
+	cs.printer.Print("  --retain-age    Keep entries for this long when running 'slog prune', e.g. '30d'")
+	cs.printer.Print("  --retain-size   Keep at most this much of the newest entries when running 'slog prune', e.g. '1GB'")
+	cs.printer.Print("  --retain-entries  Keep at most this many of the newest entries when running 'slog prune'")
+	cs.printer.Print("  --add-sink      Also write to a sink given as 'path,format=..,mode=..,min_level=..' or")
+	cs.printer.Print("                  'type=syslog,address=udp://host:514,facility=..,app_name=..' or 'type=journald' or")
+	cs.printer.Print("                  'type=webhook,url=..,header.<Name>=..,batch_size=..,timeout=..,retries=..' or")
+	cs.printer.Print("                  'type=otlp,url=..,encoding=protobuf|json,service=..' or 'type=loki,url=..,label.<name>=..'")
+	cs.printer.Print("                  with the webhook settings (repeatable)")
+	cs.printer.Print("  --remove-sink   Stop writing to the sink with this path, address or url (repeatable)")
+	cs.printer.Print("  --severities    Syslog severities of custom levels, e.g. 'success:notice,audit:warning'")
+}
+
+func displayFormat(format string) string {
+	if format == "" {
+		return FormatText
+	}
+	return format
+}
+
+func displayTemplate(template string) string {
+	if template == "" {
+		return DefaultTemplate
+	}
+	return template
+}
+
+func displayTimeFormat(timeFormat string) string {
+	if timeFormat == "" {
+		return timestampLayout
+	}
+	return timeFormat
+}
+
+func displayTimeZone(timeZone string) string {
+	if timeZone == "" {
+		return TimeZoneLocal
+	}
+	return timeZone
+}
+
+func displayLockTimeout(lockTimeout string) string {
+	if lockTimeout == "" {
+		return DefaultLockTimeout.String()
+	}
+	return lockTimeout
+}
+
+func displayRotate(config *Config) string {
+	file, err := config.mainLog()
+	if err != nil {
+		return "off"
+	}
+	pattern, err := newPeriodPattern(file)
+	if err != nil || pattern == nil {
+		return "off"
+	}
+	return "current file " + pattern.path(time.Now())
+}
+
+func displayCompress(config *Config) string {
+	if !config.Compress {
+		return "off"
+	}
+	if config.CompressAfter == "" {
+		return "on"
+	}
+	return "after " + config.CompressAfter
+}
+
+func displayRetention(config *Config) string {
+	var limits []string
+	if config.RetainAge != "" {
+		limits = append(limits, config.RetainAge)
+	}
+	if config.RetainSize != "" {
+		limits = append(limits, config.RetainSize)
+	}
+	if config.RetainEntries > 0 {
+		limits = append(limits, fmt.Sprintf("%d entries", config.RetainEntries))
+	}
+	if len(limits) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(limits, ", ")
+}
+
+func displayMaxSize(maxSize string) string {
+	if maxSize == "" {
+		return "unlimited"
+	}
+	return maxSize
 }
 
 type LogService struct {
 	configService *ConfigService
 	fs            FileSystem
 	printer       Printer
+
+	// Sinks opened by writes, closed by Close, and those that could not
+	// be opened
+	sinks     map[string]Sink
+	sinkOrder []string
+	sinkErrs  map[string]error
+
+	// Background compression of rotated files, started by the first write
+	// to each log file
+	compressing    map[string]bool
+	compression    sync.WaitGroup
+	compressionMu  sync.Mutex
+	compressionErr error
 }
 
 func NewLogService(configService *ConfigService, fs FileSystem, printer Printer) *LogService {
@@ -260,13 +694,130 @@ func NewLogService(configService *ConfigService, fs FileSystem, printer Printer)
 }
 
 func (ls *LogService) AppendLog(level, message string) error {
+	return ls.AppendLogWithFields(level, message, nil)
+}
+
+func (ls *LogService) AppendLogWithFields(level, message string, fields map[string]string) error {
+	config, err := ls.configService.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	destinations, err := ls.writeEntry(config, level, message, fields)
+	if err == nil || len(destinations) > 0 {
+		ls.printLogged(1, destinations)
+	}
+	return err
+}
+
+// AppendFromReader logs the contents of r, either as one entry per
+// non-empty line or as a single multi-line entry.
+func (ls *LogService) AppendFromReader(level string, r io.Reader, fields map[string]string, perLine bool) error {
 	config, err := ls.configService.LoadConfig()
 	if err != nil {
 		return err
 	}
 
+	if !perLine {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("error reading stdin: %w", err)
+		}
+		message := strings.TrimRight(string(data), "\r\n")
+		if message == "" {
+			return fmt.Errorf("no message provided on stdin")
+		}
+
+		destinations, err := ls.writeEntry(config, level, message, fields)
+		if err == nil || len(destinations) > 0 {
+			ls.printLogged(1, destinations)
+		}
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStdinLineSize)
+	count := 0
+	var destinations []string
+	// A sink that fails usually fails the same way for every line, so
+	// each distinct failure is reported once; the other sinks keep
+	// receiving the input.
+	var failures []error
+	seen := make(map[string]bool)
+	// Lines that cannot be logged, such as binary output, are skipped and
+	// reported together once the input is read
+	lineNo, invalid, firstInvalid := 0, 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !utf8.ValidString(line) {
+			if invalid == 0 {
+				firstInvalid = lineNo
+			}
+			invalid++
+			continue
+		}
+		written, err := ls.writeEntry(config, level, line, fields)
+		var failed *sinkError
+		if err != nil && !errors.As(err, &failed) {
+			return err
+		}
+		if err != nil && !seen[err.Error()] {
+			seen[err.Error()] = true
+			failures = append(failures, err)
+		}
+		for _, destination := range written {
+			if !slices.Contains(destinations, destination) {
+				destinations = append(destinations, destination)
+			}
+		}
+		count++
+	}
+	if invalid > 0 {
+		failures = append(failures, fmt.Errorf("skipped %d lines that are not valid UTF-8, the first at line %d", invalid, firstInvalid))
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Join(append(failures, fmt.Errorf("error reading stdin: %w", err))...)
+	}
+	if count == 0 {
+		return errors.Join(append(failures, fmt.Errorf("no message provided on stdin"))...)
+	}
+
+	if len(failures) == 0 || len(destinations) > 0 {
+		ls.printLogged(count, destinations)
+	}
+	return errors.Join(failures...)
+}
+
+// printLogged reports where count entries were written.
+func (ls *LogService) printLogged(count int, destinations []string) {
+	if len(destinations) == 0 {
+		ls.printer.PrintWarning("Nothing logged: the level is below every sink's minimum level")
+		return
+	}
+	if count == 1 {
+		ls.printer.PrintSuccess(fmt.Sprintf("Logged to %s", strings.Join(destinations, ", ")))
+		return
+	}
+	ls.printer.PrintSuccess(fmt.Sprintf("Logged %d entries to %s", count, strings.Join(destinations, ", ")))
+}
+
+// maxStdinLineSize bounds a single line read in per-line mode.
+const maxStdinLineSize = 1024 * 1024
+
+// writeEntry writes one entry to every sink that accepts its level, or to
+// the level's own file in place of the main log file, and returns where
+// it went along with the failures of the sinks it could not reach.
+func (ls *LogService) writeEntry(config *Config, level, message string, fields map[string]string) ([]string, error) {
 	if !utf8.ValidString(message) {
-		return fmt.Errorf("message contains invalid UTF-8")
+		return nil, fmt.Errorf("message contains invalid UTF-8")
+	}
+
+	if err := ValidateFields(fields); err != nil {
+		return nil, err
 	}
 
 	if level == "" {
@@ -276,76 +827,381 @@ func (ls *LogService) AppendLog(level, message string) error {
 		}
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	logEntry := fmt.Sprintf("[%s] %s: %s\n", timestamp, strings.ToUpper(level), message)
+	host, _ := os.Hostname()
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   strings.ToUpper(level),
+		Message: message,
+		Host:    host,
+		Fields:  fields,
+	}
 
-	if config.WriteMode == "prepend" {
-		// Read existing content
-		existingContent, err := ls.fs.ReadFile(config.LogFile)
-		if err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("error reading existing log file: %w", err)
+	if config.newestFirstOnDisk() {
+		if err := ls.configService.MigratePrependLog(); err != nil {
+			return nil, err
 		}
+		config.FileOrder = FileOrderOldestFirst
+	}
 
-		// Prepend new log entry
-		newContent := logEntry + string(existingContent)
-
-		// Write the combined content
-		err = ls.fs.WriteFile(config.LogFile, []byte(newContent), 0644)
+	// A failing sink does not keep the entry from the others
+	var destinations []string
+	var errs []error
+	for _, sinkConfig := range config.sinksFor(level) {
+		sink, err := ls.sink(config, sinkConfig)
 		if err != nil {
-			return fmt.Errorf("error writing to log file: %w", err)
+			errs = append(errs, &sinkError{err: err})
+			continue
 		}
-	} else {
-		// Default append mode
-		file, err := ls.fs.OpenFile(config.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		destination, err := sink.Write(entry)
 		if err != nil {
-			return fmt.Errorf("error opening log file: %w", err)
+			errs = append(errs, &sinkError{err: err})
+			continue
 		}
-		defer func() {
-			if file != nil {
-				if err := file.Close(); err != nil {
-					fmt.Printf("Warning: failed to close log file: %v\n", err)
-				}
+		destinations = append(destinations, destination)
+	}
+	return destinations, errors.Join(errs...)
+}
+
+// sinkError is a failure to write an entry to one sink. The entry may
+// still have reached the others.
+type sinkError struct {
+	err error
+}
+
+func (e *sinkError) Error() string { return e.err.Error() }
+
+func (e *sinkError) Unwrap() error { return e.err }
+
+// startCompression compresses old rotated files in the background, once
+// per log file and LogService, so the write that triggers it is not held
+// up. Wait blocks until it is done.
+func (ls *LogService) startCompression(config *Config, current string) {
+	if !config.Compress || ls.compressing[config.LogFile] {
+		return
+	}
+	if ls.compressing == nil {
+		ls.compressing = make(map[string]bool)
+	}
+	ls.compressing[config.LogFile] = true
+
+	snapshot := *config
+	ls.compression.Add(1)
+	go func() {
+		defer ls.compression.Done()
+		segments, err := compressibleSegments(ls.fs, &snapshot, current, time.Now())
+		if err != nil {
+			ls.setCompressionErr(err)
+			return
+		}
+		for _, segment := range segments {
+			if err := compressSegment(ls.fs, &snapshot, segment); err != nil {
+				ls.setCompressionErr(err)
+				return
 			}
-		}()
+		}
+	}()
+}
 
-		if file != nil {
-			_, err = file.WriteString(logEntry)
-			if err != nil {
-				return fmt.Errorf("error writing to log file: %w", err)
+// setCompressionErr records the first error from background compression.
+func (ls *LogService) setCompressionErr(err error) {
+	ls.compressionMu.Lock()
+	defer ls.compressionMu.Unlock()
+	if ls.compressionErr == nil {
+		ls.compressionErr = err
+	}
+}
+
+// Wait blocks until background work started by writes has finished and
+// returns its error, if any.
+func (ls *LogService) Wait() error {
+	ls.compression.Wait()
+	ls.compressionMu.Lock()
+	defer ls.compressionMu.Unlock()
+	return ls.compressionErr
+}
+
+// ViewOptions controls what 'slog view' shows.
+type ViewOptions struct {
+	Quiet  bool
+	Fields map[string]string
+	// Levels shows only entries at one of these levels, ignoring case
+	Levels []string
+	// MinLevel shows only entries at this level or a more severe one
+	MinLevel string
+	// Since and Until show only entries logged between two times, as
+	// accepted by parseTimeBound
+	Since string
+	Until string
+	// Grep shows only entries containing this text, or matching it as a
+	// regular expression with Regex; see Search
+	Grep       string
+	Regex      bool
+	IgnoreCase bool
+	Invert     bool
+}
+
+// Filtering reports whether any entry filter is set.
+func (o ViewOptions) Filtering() bool {
+	return len(o.Fields) > 0 || len(o.Levels) > 0 || o.MinLevel != "" || o.Since != "" || o.Until != "" || o.Grep != ""
+}
+
+// Matches reports whether an entry passes the field and level filters.
+// The minimum level needs the config's level order; see matcher.
+func (o ViewOptions) Matches(entry Entry) bool {
+	for key, value := range o.Fields {
+		actual, ok := entry.Fields[key]
+		if !ok || actual != value {
+			return false
+		}
+	}
+	if len(o.Levels) > 0 && !slices.ContainsFunc(o.Levels, func(level string) bool {
+		return strings.EqualFold(level, entry.Level)
+	}) {
+		return false
+	}
+	return true
+}
+
+// matcher returns the filter for entries, or nil when every entry is
+// shown. Entries at levels that are not ranked are left out when a
+// minimum level is set, since they cannot be compared with it.
+func (o ViewOptions) matcher(config *Config, window TimeRange) (func(Entry) bool, error) {
+	if !o.Filtering() {
+		return nil, nil
+	}
+
+	minimum := -1
+	if o.MinLevel != "" {
+		rank, ok := config.levelRank(o.MinLevel)
+		if !ok {
+			order := config.levelOrder()
+			if len(order) == 0 {
+				return nil, fmt.Errorf("unknown level %q: no level order is configured; set one with 'slog config --levels', least severe first", o.MinLevel)
 			}
+			return nil, fmt.Errorf("unknown level %q: use one of %s", o.MinLevel, strings.Join(order, ", "))
 		}
+		minimum = rank
 	}
+	return func(entry Entry) bool {
+		if !window.Contains(entry.Time) {
+			return false
+		}
+		if minimum >= 0 {
+			if rank, ok := config.levelRank(entry.Level); !ok || rank < minimum {
+				return false
+			}
+		}
+		return o.Matches(entry)
+	}, nil
+}
 
-	ls.printer.PrintSuccess(fmt.Sprintf("Logged to %s", config.LogFile))
-	return nil
+// segmentView is what one file of the log contributes to 'slog view'.
+type segmentView struct {
+	rendered       []renderedRecord
+	size           int64
+	malformed      int
+	firstMalformed string // file and line of the first malformed record
+}
+
+// viewSegment renders the entries of one file of the log that match.
+// With a time range, only the part of the file found to hold it is read.
+func viewSegment(fs FileSystem, path string, parser, renderer Formatter, match func(Entry) bool, search *Search, window TimeRange) (segmentView, error) {
+	file, err := openSegment(fs, path)
+	if err != nil {
+		return segmentView{}, err
+	}
+	defer file.Close()
+
+	start, end := int64(0), file.size
+	if !window.IsZero() {
+		start, end, err = window.search(file, file.size, parser)
+		if err != nil {
+			return segmentView{}, fmt.Errorf("error reading %s: %w", path, err)
+		}
+	}
+
+	records := NewEntryScanner(io.NewSectionReader(file, start, end-start), parser)
+	rendered, err := renderEntries(records, renderer, match, search)
+	if err != nil {
+		return segmentView{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	view := segmentView{rendered: rendered, size: file.size, malformed: records.Malformed()}
+	if first, ok := records.FirstMalformed(); ok {
+		line := first.Line
+		if start > 0 {
+			before, err := linesBefore(file, start)
+			if err != nil {
+				return segmentView{}, fmt.Errorf("error reading %s: %w", path, err)
+			}
+			line += before
+		}
+		view.firstMalformed = fmt.Sprintf("%s:%d (%v)", path, line, first.Err)
+	}
+	return view, nil
 }
 
 func (ls *LogService) ViewLogFile(quiet bool) error {
-	config, err := ls.configService.LoadConfig()
+	return ls.ViewLog(ViewOptions{Quiet: quiet})
+}
+
+func (ls *LogService) ViewLog(options ViewOptions) error {
+	quiet := options.Quiet
+
+	settings, err := ls.configService.LoadConfig()
+	if err != nil {
+		return err
+	}
+	logs, err := settings.viewLogs()
+	if err != nil {
+		return err
+	}
+	// Level files are written in the format of the main log file
+	config := logs[0]
+
+	parser, err := NewFormatter(config.Format, config.FormatOptions())
 	if err != nil {
 		return err
 	}
 
-	data, err := ls.fs.ReadFile(config.LogFile)
+	// Text files are shown in their own layout, structured formats in the
+	// default text layout. Epoch timestamps are shown in the default time
+	// layout, since a number of seconds or nanoseconds is not readable.
+	renderer := parser
+	isText := config.Format == "" || config.Format == FormatText
+	if !isText || isEpochPreset(config.TimeFormat) {
+		options := FormatOptions{TimeFormat: config.TimeFormat, TimeZone: config.TimeZone}
+		if isText {
+			options.Template = config.Template
+		}
+		if isEpochPreset(options.TimeFormat) {
+			options.TimeFormat = ""
+		}
+		renderer, err = NewFormatter(FormatText, options)
+		if err != nil {
+			return err
+		}
+	}
+	window, err := options.timeRange(settings, time.Now())
 	if err != nil {
-		return fmt.Errorf("error reading log file: %w", err)
+		return err
+	}
+	match, err := options.matcher(settings, window)
+	if err != nil {
+		return err
+	}
+	search, err := options.search()
+	if err != nil {
+		return err
+	}
+
+	// Each file of the log is read oldest first, so malformed records can
+	// be reported by file and line.
+	var sources [][]renderedRecord
+	var names []string
+	var malformed int
+	var firstMalformed string
+	empty := true
+	for _, log := range logs {
+		paths, err := logSegments(ls.fs, log)
+		if err != nil {
+			return err
+		}
+		// An entry is stamped before its writer waits for the lock, so
+		// entries can be stored out of order by up to the lock timeout.
+		// The search covers that much more, and match keeps the entries
+		// in the range itself.
+		lockTimeout, err := parseLockTimeout(log.LockTimeout)
+		if err != nil {
+			return err
+		}
+		logWindow := window.widen(lockTimeout)
+		// Files in the old prepend layout are not in time order, so a
+		// time range cannot be searched for in them
+		if log.newestFirstOnDisk() {
+			logWindow = TimeRange{}
+		}
+
+		var records []renderedRecord
+		found := log == config
+		for _, path := range paths {
+			view, err := viewSegment(ls.fs, path, parser, renderer, match, search, logWindow)
+			if err != nil {
+				// A level's file is only created by its first entry
+				if log != config && errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return err
+			}
+			found = true
+			if view.size > 0 {
+				empty = false
+			}
+			records = append(records, view.rendered...)
+			if view.malformed > 0 && malformed == 0 {
+				firstMalformed = view.firstMalformed
+			}
+			malformed += view.malformed
+		}
+		if log.newestFirstOnDisk() {
+			slices.Reverse(records)
+		}
+		if !found {
+			continue
+		}
+		sources = append(sources, records)
+		names = append(names, log.LogFile)
 	}
 
-	if len(data) == 0 {
+	if empty {
 		if !quiet {
-			ls.printer.Print(Bold + "Log file is empty: " + Reset + config.LogFile)
+			ls.printer.Print(Bold + "Log file is empty: " + Reset + strings.Join(names, ", "))
 		}
 		return nil
 	}
 
+	// The files of routed levels are merged into the main log by time.
+	// Prepend mode shows the newest entry first.
+	output := mergeRecords(sources)
+	if config.WriteMode == "prepend" {
+		slices.Reverse(output)
+	}
+
 	if !quiet {
-		ls.printer.Print(Bold + "Log file contents: " + Reset + config.LogFile)
+		ls.printer.Print(Bold + "Log file contents: " + Reset + strings.Join(names, ", "))
 		ls.printer.Print("")
 	}
-	ls.printer.Print(string(data))
+	ls.printer.Print(strings.Join(output, ""))
+
+	if malformed > 0 && !quiet {
+		if match != nil {
+			ls.printer.PrintWarning(fmt.Sprintf("Skipped %d records that could not be parsed, the first at %s", malformed, firstMalformed))
+		} else {
+			ls.printer.PrintWarning(fmt.Sprintf("%d records could not be parsed and are shown as stored, the first at %s", malformed, firstMalformed))
+		}
+	}
 	return nil
 }
 
+// mergeRecords merges the records of several files, each oldest first,
+// into one list in time order. Records with the same time are kept in the
+// order of the files.
+func mergeRecords(sources [][]renderedRecord) []string {
+	var merged []string
+	for {
+		next := -1
+		for i, records := range sources {
+			if len(records) > 0 && (next < 0 || records[0].Time.Before(sources[next][0].Time)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return merged
+		}
+		merged = append(merged, sources[next][0].Text)
+		sources[next] = sources[next][1:]
+	}
+}
+
 type App struct {
 	configService *ConfigService
 	logService    *LogService
@@ -370,10 +1226,22 @@ func (app *App) HandleConfig(logFile string, logLevels map[string]string, defaul
 	return app.configService.SaveConfig(logFile, logLevels, defaultLevel, writeMode)
 }
 
+func (app *App) HandleConfigUpdate(update ConfigUpdate) error {
+	return app.configService.UpdateConfig(update)
+}
+
 func (app *App) HandleView(quiet bool) error {
 	return app.logService.ViewLogFile(quiet)
 }
 
+func (app *App) HandleViewLog(options ViewOptions) error {
+	return app.logService.ViewLog(options)
+}
+
+func (app *App) HandlePrune(options PruneOptions) error {
+	return app.logService.Prune(options)
+}
+
 func (app *App) HandleConfigView() error {
 	err := app.configService.ViewConfig()
 	if err != nil {
@@ -385,7 +1253,33 @@ func (app *App) HandleConfigView() error {
 }
 
 func (app *App) HandleLog(level, message string) error {
-	return app.logService.AppendLog(level, message)
+	err := app.logService.AppendLog(level, message)
+	app.waitForLogService()
+	return err
+}
+
+func (app *App) HandleStdinLog(level string, r io.Reader, fields map[string]string, perLine bool) error {
+	err := app.logService.AppendFromReader(level, r, fields, perLine)
+	app.waitForLogService()
+	return err
+}
+
+func (app *App) HandleLogWithFields(level, message string, fields map[string]string) error {
+	err := app.logService.AppendLogWithFields(level, message, fields)
+	app.waitForLogService()
+	return err
+}
+
+// waitForLogService closes the sinks and lets background compression
+// finish before exiting. The entry is already written, so a failure is
+// only a warning.
+func (app *App) waitForLogService() {
+	if err := app.logService.Close(); err != nil {
+		app.printer.PrintWarning(fmt.Sprintf("Could not close log sinks: %v", err))
+	}
+	if err := app.logService.Wait(); err != nil {
+		app.printer.PrintWarning(fmt.Sprintf("Could not compress rotated log files: %v", err))
+	}
 }
 
 func (app *App) ShowVersion() {
@@ -404,10 +1298,12 @@ func (app *App) ShowHelp() {
 	app.printer.Print(Bold + "Commands:" + Reset)
 	app.printer.Print("  config    Show current configuration and usage, or set new configuration")
 	app.printer.Print("  view      View log file contents")
+	app.printer.Print("  prune     Remove entries beyond the retention policy (--dry-run to preview)")
 	app.printer.Print("  help      Show this help message")
 	app.printer.Print("")
 	app.printer.Print(Bold + "Usage:" + Reset)
-	app.printer.Print("  slog [level-flag] <message>")
+	app.printer.Print("  slog [level-flag] <message> [--field key=value ...] [-- key=value ...]")
+	app.printer.Print("  <command> | slog [level-flag] [--per-line]")
 	app.printer.Print("")
 	app.printer.Print(Bold + "Flags:" + Reset)
 	app.printer.Print("  --version, -v    Show version information")
@@ -417,29 +1313,162 @@ func (app *App) ShowHelp() {
 	app.printer.Print("  slog config                                                    # Show current config and usage")
 	app.printer.Print("  slog config --file ./app.log --levels 'info:i,warn:w,error:e' --default info --mode append")
 	app.printer.Print("  slog config -f ./app.log -l 'info:i,warn:w,error:e' -d info -m prepend")
+	app.printer.Print("  slog config --format jsonl                                     # Write entries as JSON Lines")
+	app.printer.Print("  slog config --add-sink './errors.log,min_level=error'          # Also copy errors to a second file")
 	app.printer.Print("  slog view                                                      # View log file contents")
 	app.printer.Print("  slog view --quiet                                              # View log file contents without header")
 	app.printer.Print("  slog \"Application started\"")
 	app.printer.Print("  slog -i \"Info message\"")
 	app.printer.Print("  slog -w \"Warning message\"")
+	app.printer.Print("  slog -e \"Deploy failed\" --field service=api --field region=eu")
+	app.printer.Print("  slog -e \"Deploy failed\" -- service=api region=eu")
+	app.printer.Print("  make 2>&1 | slog -e --per-line                                 # One entry per input line")
+	app.printer.Print("  cat trace.txt | slog -e                                        # Whole input as one entry")
+	app.printer.Print("  slog view --field service=api                                  # Only show entries with a field")
+	app.printer.Print("  slog view --level error                                        # Only show errors")
+	app.printer.Print("  slog view --min-level warn                                     # Show warnings and anything more severe")
+	app.printer.Print("  slog view --since 1h                                           # Show the last hour")
+	app.printer.Print("  slog view --since yesterday --until today                      # Show yesterday's entries")
+	app.printer.Print("  slog view --grep timeout -i                                    # Show entries mentioning a timeout")
+	app.printer.Print("  slog view --grep 'user=\\d+' --regex                            # Search with a regular expression")
+	app.printer.Print("  slog view --grep healthcheck --invert                          # Hide matching entries")
+	app.printer.Print("  slog prune --retain-age 30d --dry-run                          # Show what pruning would remove")
 }
 
-func parseLevels(levelsStr string) map[string]string {
-	levels := make(map[string]string)
+// parseLevels parses 'level:flag,...', where a level may name its own
+// file as 'level:flag:file'.
+func parseLevels(levelsStr string) map[string]LevelConfig {
+	levels := make(map[string]LevelConfig)
 	if levelsStr == "" {
 		return levels
 	}
 
 	pairs := strings.Split(levelsStr, ",")
 	for _, pair := range pairs {
-		parts := strings.Split(strings.TrimSpace(pair), ":")
-		if len(parts) == 2 {
-			levels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		level := LevelConfig{Flag: strings.TrimSpace(parts[1])}
+		if len(parts) == 3 {
+			level.File = strings.TrimSpace(parts[2])
 		}
+		levels[strings.TrimSpace(parts[0])] = level
 	}
 	return levels
 }
 
+// logArgs holds the parsed arguments of a log invocation.
+type logArgs struct {
+	Level   string
+	Message string
+	Fields  map[string]string
+	PerLine bool
+}
+
+// parseLogArgs splits the arguments of a log invocation into the level,
+// the message and any structured fields. Fields are given with
+// '--field key=value' or as key=value tokens after a '--' separator.
+func parseLogArgs(args []string, logLevels map[string]LevelConfig) (logArgs, error) {
+	var parsed logArgs
+	if len(args) > 0 {
+		for levelName, level := range logLevels {
+			if args[0] == "-"+level.Flag || args[0] == "--"+levelName {
+				parsed.Level = levelName
+				args = args[1:]
+				break
+			}
+		}
+	}
+
+	fields := fieldFlags{}
+	var words []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			for _, pair := range args[i+1:] {
+				if err := fields.Set(pair); err != nil {
+					return logArgs{}, err
+				}
+			}
+			i = len(args)
+		case arg == "--field":
+			if i+1 >= len(args) {
+				return logArgs{}, fmt.Errorf("--field requires a key=value argument")
+			}
+			i++
+			if err := fields.Set(args[i]); err != nil {
+				return logArgs{}, err
+			}
+		case strings.HasPrefix(arg, "--field="):
+			if err := fields.Set(strings.TrimPrefix(arg, "--field=")); err != nil {
+				return logArgs{}, err
+			}
+		case arg == "--per-line":
+			parsed.PerLine = true
+		default:
+			words = append(words, arg)
+		}
+	}
+
+	if len(fields) > 0 {
+		parsed.Fields = fields
+	}
+	parsed.Message = strings.Join(words, " ")
+	return parsed, nil
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal.
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// fieldFlags collects repeated key=value flags.
+type fieldFlags map[string]string
+
+func (f fieldFlags) String() string {
+	return formatFields(f)
+}
+
+func (f fieldFlags) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid field %q: expected key=value", pair)
+	}
+	f[key] = value
+	return nil
+}
+
+// optionalBool is a boolean flag that also records whether it was given.
+type optionalBool struct {
+	value *bool
+}
+
+func (b *optionalBool) String() string {
+	if b == nil || b.value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.value = &v
+	return nil
+}
+
+func (b *optionalBool) IsBoolFlag() bool {
+	return true
+}
+
 func main() {
 	app := NewApp()
 
@@ -457,16 +1486,55 @@ func main() {
 	configCmd := flag.NewFlagSet("config", flag.ExitOnError)
 	logFile := configCmd.String("file", "", "Path to log file")
 	logFileShort := configCmd.String("f", "", "Path to log file (short)")
-	logLevelsStr := configCmd.String("levels", "", "Log levels in format 'level:flag,level:flag' (e.g. 'info:i,warn:w,error:e')")
+	logLevelsStr := configCmd.String("levels", "", "Log levels in format 'level:flag,level:flag', least severe first (e.g. 'info:i,warn:w,error:e'); 'level:flag:file' routes a level to its own file")
 	logLevelsShort := configCmd.String("l", "", "Log levels in format 'level:flag,level:flag' (short)")
 	defaultLevel := configCmd.String("default", "", "Default log level when no level flag is provided")
 	defaultLevelShort := configCmd.String("d", "", "Default log level when no level flag is provided (short)")
 	writeMode := configCmd.String("mode", "", "Write mode: 'append' (default) or 'prepend'")
 	writeModeShort := configCmd.String("m", "", "Write mode: 'append' (default) or 'prepend' (short)")
+	format := configCmd.String("format", "", "Entry format: 'text' (default), 'jsonl' or 'logfmt'")
+	formatShort := configCmd.String("o", "", "Entry format: 'text' (default), 'jsonl' or 'logfmt' (short)")
+	lineTemplate := configCmd.String("template", "", "Line layout for the text format, e.g. '[{{.Time}}] {{.Level}}: {{.Message}}'")
+	lineTemplateShort := configCmd.String("t", "", "Line layout for the text format (short)")
+	timeFormat := configCmd.String("time-format", "", "Timestamp layout or preset: rfc3339, rfc3339nano, unix, unixmilli, unixmicro, unixnano")
+	timeZone := configCmd.String("time-zone", "", "Timestamp zone: 'local' (default), 'utc' or an IANA zone name")
+	lockTimeout := configCmd.String("lock-timeout", "", "How long to wait for the log file lock, e.g. '10s'")
+	maxSize := configCmd.String("max-size", "", "Rotate the log file before it grows past this size, e.g. '10MB'")
+	maxBackups := configCmd.Int("max-backups", 0, "Number of rotated log files to keep (default 5)")
+	rotate := configCmd.String("rotate", "", "Start a new dated log file 'daily' or 'hourly'")
+	var compress optionalBool
+	configCmd.Var(&compress, "compress", "Gzip rotated log files")
+	compressAfter := configCmd.String("compress-after", "", "Only compress rotated log files older than this, e.g. '24h'")
+	retainAge := configCmd.String("retain-age", "", "Retention for 'slog prune': keep entries for this long, e.g. '30d'")
+	retainSize := configCmd.String("retain-size", "", "Retention for 'slog prune': keep at most this much of the newest entries, e.g. '1GB'")
+	retainEntries := configCmd.Int("retain-entries", 0, "Retention for 'slog prune': keep at most this many of the newest entries")
+	var addSinks sinkFlags
+	configCmd.Var(&addSinks, "add-sink", "Also write entries to this sink, e.g. './errors.log,min_level=error' (repeatable)")
+	var removeSinks listFlags
+	configCmd.Var(&removeSinks, "remove-sink", "Stop writing to the sink with this path or address (repeatable)")
+	severitiesStr := configCmd.String("severities", "", "Syslog severities of custom levels in format 'level:severity,...', e.g. 'success:notice'")
 
 	viewCmd := flag.NewFlagSet("view", flag.ExitOnError)
 	quietFlag := viewCmd.Bool("quiet", false, "Don't show header, just log contents")
 	quietFlagShort := viewCmd.Bool("q", false, "Don't show header, just log contents (short)")
+	viewFields := fieldFlags{}
+	viewCmd.Var(viewFields, "field", "Only show entries with this key=value field (repeatable)")
+	viewLevels := viewCmd.String("level", "", "Only show entries at these levels, e.g. 'error' or 'warn,error'")
+	viewMinLevel := viewCmd.String("min-level", "", "Only show entries at this level or a more severe one, e.g. 'warn'")
+	viewSince := viewCmd.String("since", "", "Only show entries logged at or after a time, e.g. '2h', 'yesterday' or '2024-01-15 10:30'")
+	viewUntil := viewCmd.String("until", "", "Only show entries logged at or before a time, e.g. '30m', 'today' or '2024-01-15 12:00'")
+	viewGrep := viewCmd.String("grep", "", "Only show entries containing this text, highlighting it")
+	viewRegex := viewCmd.Bool("regex", false, "Match the --grep pattern as a regular expression")
+	viewIgnoreCase := viewCmd.Bool("ignore-case", false, "Ignore case when matching the --grep pattern")
+	viewIgnoreCaseShort := viewCmd.Bool("i", false, "Ignore case when matching the --grep pattern (short)")
+	viewInvert := viewCmd.Bool("invert", false, "Only show entries that do not match the --grep pattern")
+	viewInvertShort := viewCmd.Bool("v", false, "Only show entries that do not match the --grep pattern (short)")
+	pruneCmd := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := pruneCmd.Bool("dry-run", false, "Report what would be removed without changing anything")
+	dryRunShort := pruneCmd.Bool("n", false, "Report what would be removed without changing anything (short)")
+	pruneAge := pruneCmd.String("retain-age", "", "Keep entries for this long, e.g. '30d', instead of the configured age")
+	pruneSize := pruneCmd.String("retain-size", "", "Keep at most this much of the newest entries instead of the configured size")
+	pruneEntries := pruneCmd.Int("retain-entries", 0, "Keep at most this many of the newest entries instead of the configured count")
 	helpCmd := flag.NewFlagSet("help", flag.ExitOnError)
 
 	if len(os.Args) < 2 {
@@ -514,8 +1582,18 @@ func main() {
 			finalWriteMode = *writeModeShort
 		}
 
+		finalFormat := *format
+		if finalFormat == "" {
+			finalFormat = *formatShort
+		}
+
+		finalTemplate := *lineTemplate
+		if finalTemplate == "" {
+			finalTemplate = *lineTemplateShort
+		}
+
 		// Check if any config parameters were provided
-		if finalLogFile == "" && finalLevelsStr == "" && finalDefaultLevel == "" && finalWriteMode == "" {
+		if finalLogFile == "" && finalLevelsStr == "" && finalDefaultLevel == "" && finalWriteMode == "" && finalFormat == "" && finalTemplate == "" && *timeFormat == "" && *timeZone == "" && *lockTimeout == "" && *maxSize == "" && *maxBackups == 0 && *rotate == "" && compress.value == nil && *compressAfter == "" && *retainAge == "" && *retainSize == "" && *retainEntries == 0 && len(addSinks) == 0 && len(removeSinks) == 0 && *severitiesStr == "" {
 			// Show current config and usage when no parameters provided
 			err = app.HandleConfigView()
 			if err != nil {
@@ -525,11 +1603,43 @@ func main() {
 			return
 		}
 
-		var levels map[string]string
+		var levels map[string]LevelConfig
+		var levelOrder []string
 		if finalLevelsStr != "" {
 			levels = parseLevels(finalLevelsStr)
+			levelOrder = parseLevelOrder(finalLevelsStr)
+		}
+		var severities map[string]string
+		if *severitiesStr != "" {
+			severities, err = parseSeverities(*severitiesStr)
+			if err != nil {
+				app.printer.PrintError(err.Error())
+				os.Exit(1)
+			}
 		}
-		err = app.HandleConfig(finalLogFile, levels, finalDefaultLevel, finalWriteMode)
+		err = app.HandleConfigUpdate(ConfigUpdate{
+			LogFile:       finalLogFile,
+			LogLevels:     levels,
+			LevelOrder:    levelOrder,
+			DefaultLevel:  finalDefaultLevel,
+			WriteMode:     finalWriteMode,
+			Format:        finalFormat,
+			Template:      finalTemplate,
+			TimeFormat:    *timeFormat,
+			TimeZone:      *timeZone,
+			LockTimeout:   *lockTimeout,
+			MaxSize:       *maxSize,
+			MaxBackups:    *maxBackups,
+			Rotate:        *rotate,
+			Compress:      compress.value,
+			CompressAfter: *compressAfter,
+			RetainAge:     *retainAge,
+			RetainSize:    *retainSize,
+			RetainEntries: *retainEntries,
+			AddSinks:      addSinks,
+			RemoveSinks:   removeSinks,
+			Severities:    severities,
+		})
 	case "view":
 		err = viewCmd.Parse(os.Args[2:])
 		if err != nil {
@@ -539,7 +1649,37 @@ func main() {
 
 		// Use either long or short form for quiet flag
 		quiet := *quietFlag || *quietFlagShort
-		err = app.HandleView(quiet)
+		var levels []string
+		for _, level := range strings.Split(*viewLevels, ",") {
+			if level = strings.TrimSpace(level); level != "" {
+				levels = append(levels, level)
+			}
+		}
+		err = app.HandleViewLog(ViewOptions{
+			Quiet:      quiet,
+			Fields:     viewFields,
+			Levels:     levels,
+			MinLevel:   strings.TrimSpace(*viewMinLevel),
+			Since:      strings.TrimSpace(*viewSince),
+			Until:      strings.TrimSpace(*viewUntil),
+			Grep:       *viewGrep,
+			Regex:      *viewRegex,
+			IgnoreCase: *viewIgnoreCase || *viewIgnoreCaseShort,
+			Invert:     *viewInvert || *viewInvertShort,
+		})
+	case "prune":
+		err = pruneCmd.Parse(os.Args[2:])
+		if err != nil {
+			app.printer.PrintError(fmt.Sprintf("Error parsing prune arguments: %v", err))
+			os.Exit(1)
+		}
+
+		err = app.HandlePrune(PruneOptions{
+			DryRun:        *dryRun || *dryRunShort,
+			RetainAge:     *pruneAge,
+			RetainSize:    *pruneSize,
+			RetainEntries: *pruneEntries,
+		})
 	case "help":
 		err = helpCmd.Parse(os.Args[2:])
 		if err != nil {
@@ -555,30 +1695,22 @@ func main() {
 			os.Exit(1)
 		}
 
-		level := ""
-		message := ""
-
-		if len(os.Args) == 2 {
-			message = os.Args[1]
-		} else if len(os.Args) >= 3 {
-			for levelName, flagName := range config.LogLevels {
-				if os.Args[1] == "-"+flagName || os.Args[1] == "--"+levelName {
-					level = levelName
-					message = strings.Join(os.Args[2:], " ")
-					break
-				}
-			}
-			if level == "" {
-				message = strings.Join(os.Args[1:], " ")
-			}
+		args, parseErr := parseLogArgs(os.Args[1:], config.LogLevels)
+		if parseErr != nil {
+			app.printer.PrintError(parseErr.Error())
+			os.Exit(1)
 		}
 
-		if message == "" {
-			app.printer.PrintError("No message provided")
-			os.Exit(1)
+		if args.Message == "" {
+			if !stdinIsPiped() {
+				app.printer.PrintError("No message provided")
+				os.Exit(1)
+			}
+			err = app.HandleStdinLog(args.Level, os.Stdin, args.Fields, args.PerLine)
+			break
 		}
 
-		err = app.HandleLog(level, message)
+		err = app.HandleLogWithFields(args.Level, args.Message, args.Fields)
 	}
 
 	if err != nil {
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 	"unicode/utf8"
 )
 
@@ -24,12 +31,39 @@ type MockFileSystem struct {
 	writeFiles map[string][]byte // Track what was written
 	openErr    error
 	openedFile *MockFile
+	lockErr    error
+	locks      []*MockLock // Locks handed out, in order
+
+	// Atomic replace steps; tempFile, when set, is handed out by CreateTemp
+	statErr       error
+	createTempErr error
+	tempFile      *MockFile
+	tempFiles     map[string]*MockFile // Temporary files not yet renamed or removed
+	chmodErr      error
+	renameErr     error
+	modes         map[string]os.FileMode
+	removed       []string
+}
+
+type MockLock struct {
+	path     string
+	released bool
+}
+
+func (m *MockLock) Close() error {
+	m.released = true
+	return nil
 }
 
 type MockFile struct {
+	name        string
 	writeErr    error
+	syncErr     error
+	closeErr    error
 	writtenData string
+	synced      bool
 	closed      bool
+	onClose     func(*MockFile)
 }
 
 func (m *MockFile) WriteString(s string) (int, error) {
@@ -40,16 +74,51 @@ func (m *MockFile) WriteString(s string) (int, error) {
 	return len(s), nil
 }
 
+func (m *MockFile) Write(p []byte) (int, error) {
+	return m.WriteString(string(p))
+}
+
+func (m *MockFile) Sync() error {
+	if m.syncErr != nil {
+		return m.syncErr
+	}
+	m.synced = true
+	return nil
+}
+
 func (m *MockFile) Close() error {
 	m.closed = true
-	return nil
+	if m.onClose != nil {
+		m.onClose(m)
+	}
+	return m.closeErr
+}
+
+func (m *MockFile) Name() string {
+	return m.name
 }
 
+// MockFileInfo reports a name, size and permissions for Stat
+type MockFileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (m MockFileInfo) Name() string       { return m.name }
+func (m MockFileInfo) Size() int64        { return m.size }
+func (m MockFileInfo) Mode() os.FileMode  { return m.mode }
+func (m MockFileInfo) ModTime() time.Time { return time.Time{} }
+func (m MockFileInfo) IsDir() bool        { return false }
+func (m MockFileInfo) Sys() interface{}   { return nil }
+
 func NewMockFileSystem() *MockFileSystem {
 	return &MockFileSystem{
 		writeFiles: make(map[string][]byte),
 		readFiles:  make(map[string][]byte),
 		openedFile: &MockFile{},
+		tempFiles:  make(map[string]*MockFile),
+		modes:      make(map[string]os.FileMode),
 	}
 }
 
@@ -94,12 +163,145 @@ func (m *MockFileSystem) ReadFile(filename string) ([]byte, error) {
 	return nil, os.ErrNotExist
 }
 
-func (m *MockFileSystem) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+// Open serves the same contents as ReadFile
+func (m *MockFileSystem) Open(name string) (ReadableFile, error) {
+	data, err := m.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &MockReadableFile{Reader: bytes.NewReader(data), name: name}, nil
+}
+
+// MockReadableFile reads a file's contents from memory
+type MockReadableFile struct {
+	*bytes.Reader
+	name string
+}
+
+func (f *MockReadableFile) Close() error { return nil }
+
+func (f *MockReadableFile) Stat() (os.FileInfo, error) {
+	return MockFileInfo{name: filepath.Base(f.name), size: f.Size(), mode: 0644}, nil
+}
+
+func (m *MockFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
 	if m.openErr != nil {
 		return nil, m.openErr
 	}
-	// We can't return a real *os.File from mock, but we'll test the logic separately
-	return nil, nil
+	file := &MockFile{name: name}
+	if m.openedFile != nil {
+		file.writeErr = m.openedFile.writeErr
+	}
+	// Appended data becomes visible to ReadFile once the file is closed
+	file.onClose = func(f *MockFile) {
+		data := append(append([]byte{}, m.readFiles[name]...), f.writtenData...)
+		m.writeFiles[name] = data
+		m.readFiles[name] = data
+	}
+	return file, nil
+}
+
+func (m *MockFileSystem) Lock(path string, timeout time.Duration) (io.Closer, error) {
+	if m.lockErr != nil {
+		return nil, m.lockErr
+	}
+	lock := &MockLock{path: path}
+	m.locks = append(m.locks, lock)
+	return lock, nil
+}
+
+func (m *MockFileSystem) CreateTemp(dir, pattern string) (File, error) {
+	if m.createTempErr != nil {
+		return nil, m.createTempErr
+	}
+	file := m.tempFile
+	if file == nil {
+		file = &MockFile{}
+	}
+	file.name = filepath.Join(dir, strings.Replace(pattern, "*", "0", 1))
+	m.tempFiles[file.name] = file
+	return file, nil
+}
+
+func (m *MockFileSystem) Rename(oldpath, newpath string) error {
+	if m.renameErr != nil {
+		return m.renameErr
+	}
+	var data []byte
+	if file, exists := m.tempFiles[oldpath]; exists {
+		data = []byte(file.writtenData)
+		delete(m.tempFiles, oldpath)
+	} else if content, exists := m.readFiles[oldpath]; exists {
+		data = content
+		delete(m.readFiles, oldpath)
+		delete(m.writeFiles, oldpath)
+	} else {
+		return os.ErrNotExist
+	}
+	m.modes[newpath] = m.modes[oldpath]
+	delete(m.modes, oldpath)
+	m.writeFiles[newpath] = data
+	m.readFiles[newpath] = data
+	return nil
+}
+
+func (m *MockFileSystem) Stat(name string) (os.FileInfo, error) {
+	if m.statErr != nil {
+		return nil, m.statErr
+	}
+	data, exists := m.readFiles[name]
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+	mode, exists := m.modes[name]
+	if !exists {
+		mode = 0644
+	}
+	return MockFileInfo{name: filepath.Base(name), size: int64(len(data)), mode: mode}, nil
+}
+
+func (m *MockFileSystem) Chmod(name string, mode os.FileMode) error {
+	if m.chmodErr != nil {
+		return m.chmodErr
+	}
+	m.modes[name] = mode
+	return nil
+}
+
+func (m *MockFileSystem) Remove(name string) error {
+	delete(m.tempFiles, name)
+	delete(m.readFiles, name)
+	delete(m.writeFiles, name)
+	m.removed = append(m.removed, name)
+	return nil
+}
+
+// ReadDir lists the files in readFiles that are directly inside name
+func (m *MockFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	var entries []os.DirEntry
+	for path, data := range m.readFiles {
+		if filepath.Dir(path) == name {
+			info := MockFileInfo{name: filepath.Base(path), size: int64(len(data)), mode: 0644}
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// TempHomeFileSystem is a RealFileSystem whose home directory is a test's
+// temporary directory, for tests that need real files.
+type TempHomeFileSystem struct {
+	RealFileSystem
+	home string
+}
+
+func NewTempHomeFileSystem(t testing.TB) *TempHomeFileSystem {
+	return &TempHomeFileSystem{home: t.TempDir()}
+}
+
+func (fs *TempHomeFileSystem) UserHomeDir() (string, error) {
+	return fs.home, nil
 }
 
 // MockPrinter implements Printer interface for testing
@@ -163,8 +365,8 @@ func TestConfigService_SaveConfig(t *testing.T) {
 			},
 			expectError: false,
 			expectedConfig: &Config{
-				LogFile:      "/tmp/test.log",
-				LogLevels:    map[string]string{"info": "i", "warn": "w"},
+				Sinks:        []SinkConfig{{Path: "/tmp/test.log"}},
+				LogLevels:    map[string]LevelConfig{"info": {Flag: "i"}, "warn": {Flag: "w"}},
 				DefaultLevel: "info",
 			},
 		},
@@ -175,19 +377,19 @@ func TestConfigService_SaveConfig(t *testing.T) {
 			defaultLevel: "",
 			existingConfig: &Config{
 				LogFile:      "/tmp/old.log",
-				LogLevels:    map[string]string{"debug": "d"},
+				LogLevels:    map[string]LevelConfig{"debug": {Flag: "d"}},
 				DefaultLevel: "debug",
 			},
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/old.log", LogLevels: map[string]string{"debug": "d"}, DefaultLevel: "debug"}
+				config := Config{LogFile: "/tmp/old.log", LogLevels: map[string]LevelConfig{"debug": {Flag: "d"}}, DefaultLevel: "debug"}
 				configJSON, _ := json.Marshal(config)
 				fs.readData = configJSON
 			},
 			expectError: false,
 			expectedConfig: &Config{
-				LogFile:      "/tmp/new.log",
-				LogLevels:    map[string]string{"debug": "d"},
+				Sinks:        []SinkConfig{{Path: "/tmp/new.log"}},
+				LogLevels:    map[string]LevelConfig{"debug": {Flag: "d"}},
 				DefaultLevel: "debug",
 			},
 		},
@@ -198,19 +400,19 @@ func TestConfigService_SaveConfig(t *testing.T) {
 			defaultLevel: "",
 			existingConfig: &Config{
 				LogFile:      "/tmp/existing.log",
-				LogLevels:    map[string]string{"info": "i"},
+				LogLevels:    map[string]LevelConfig{"info": {Flag: "i"}},
 				DefaultLevel: "info",
 			},
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/existing.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/existing.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				fs.readData = configJSON
 			},
 			expectError: false,
 			expectedConfig: &Config{
-				LogFile:      "/tmp/existing.log",
-				LogLevels:    map[string]string{"error": "e", "fatal": "f"},
+				Sinks:        []SinkConfig{{Path: "/tmp/existing.log"}},
+				LogLevels:    map[string]LevelConfig{"error": {Flag: "e"}, "fatal": {Flag: "f"}},
 				DefaultLevel: "info",
 			},
 		},
@@ -225,8 +427,8 @@ func TestConfigService_SaveConfig(t *testing.T) {
 			},
 			expectError: false, // Should use default log file
 			expectedConfig: &Config{
-				LogFile:      "./log.txt",
-				LogLevels:    map[string]string{"info": "i"},
+				Sinks:        []SinkConfig{{Path: "./log.txt"}},
+				LogLevels:    map[string]LevelConfig{"info": {Flag: "i"}},
 				DefaultLevel: "info",
 			},
 		},
@@ -295,8 +497,11 @@ func TestConfigService_SaveConfig(t *testing.T) {
 						t.Errorf("Failed to unmarshal written config: %v", err)
 					} else {
 						if tt.expectedConfig != nil {
-							if config.LogFile != tt.expectedConfig.LogFile {
-								t.Errorf("Expected log file %q, got %q", tt.expectedConfig.LogFile, config.LogFile)
+							if !reflect.DeepEqual(config.Sinks, tt.expectedConfig.Sinks) {
+								t.Errorf("Expected sinks %v, got %v", tt.expectedConfig.Sinks, config.Sinks)
+							}
+							if config.LogFile != "" {
+								t.Errorf("Expected no legacy log file, got %q", config.LogFile)
 							}
 							if len(config.LogLevels) != len(tt.expectedConfig.LogLevels) {
 								t.Errorf("Expected %d log levels, got %d", len(tt.expectedConfig.LogLevels), len(config.LogLevels))
@@ -338,8 +543,8 @@ func TestConfigService_LoadConfig(t *testing.T) {
 			},
 			expectErr: false,
 			expected: &Config{
-				LogFile:   "/tmp/test.log",
-				LogLevels: map[string]string{"info": "i", "warn": "w"},
+				Sinks:     []SinkConfig{{Path: "/tmp/test.log"}},
+				LogLevels: map[string]LevelConfig{"info": {Flag: "i"}, "warn": {Flag: "w"}},
 			},
 		},
 		{
@@ -389,8 +594,8 @@ func TestConfigService_LoadConfig(t *testing.T) {
 				if err != nil {
 					t.Errorf("Expected no error, got %v", err)
 				}
-				if config.LogFile != tt.expected.LogFile {
-					t.Errorf("Expected log file %q, got %q", tt.expected.LogFile, config.LogFile)
+				if !reflect.DeepEqual(config.Sinks, tt.expected.Sinks) {
+					t.Errorf("Expected sinks %v, got %v", tt.expected.Sinks, config.Sinks)
 				}
 				if len(config.LogLevels) != len(tt.expected.LogLevels) {
 					t.Errorf("Expected %d log levels, got %d", len(tt.expected.LogLevels), len(config.LogLevels))
@@ -475,7 +680,7 @@ func TestLogService_AppendLog(t *testing.T) {
 			message: "test warning message",
 			setupMocks: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"warn": "w"}}
+				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"warn": {Flag: "w"}}}
 				configJSON, _ := json.Marshal(config)
 				fs.readData = configJSON
 			},
@@ -488,7 +693,7 @@ func TestLogService_AppendLog(t *testing.T) {
 			message: "test info message",
 			setupMocks: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}}
+				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}}
 				configJSON, _ := json.Marshal(config)
 				fs.readData = configJSON
 			},
@@ -501,7 +706,7 @@ func TestLogService_AppendLog(t *testing.T) {
 			message: string([]byte{0xff, 0xfe, 0xfd}),
 			setupMocks: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}}
+				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}}
 				configJSON, _ := json.Marshal(config)
 				fs.readData = configJSON
 			},
@@ -525,7 +730,7 @@ func TestLogService_AppendLog(t *testing.T) {
 			message: "test message",
 			setupMocks: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}}
+				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}}
 				configJSON, _ := json.Marshal(config)
 				fs.readData = configJSON
 				fs.openErr = errors.New("permission denied")
@@ -568,91 +773,75 @@ func TestLogService_AppendLog(t *testing.T) {
 
 // Test prepend functionality
 func TestLogService_PrependLog(t *testing.T) {
+	const (
+		oldest = "[2023-01-01 12:00:00] INFO: oldest message\n"
+		older  = "[2023-01-01 12:01:00] WARN: older message\n"
+	)
+
 	tests := []struct {
 		name          string
-		level         string
-		message       string
+		fileOrder     string
 		existingData  string
 		setupMocks    func(*MockFileSystem)
 		expectErr     bool
 		errorMsg      string
-		expectedLevel string
+		expectedOrder []string // Messages in the order they are stored
 	}{
 		{
-			name:         "successful prepend to existing file",
-			level:        "warn",
-			message:      "new warning",
-			existingData: "[2023-01-01 12:00:00] INFO: old message\n",
-			setupMocks: func(fs *MockFileSystem) {
-				fs.homeDir = "/tmp"
-				config := Config{
-					LogFile:   "/tmp/test.log",
-					LogLevels: map[string]string{"warn": "w"},
-					WriteMode: "prepend",
-				}
-				configJSON, _ := json.Marshal(config)
-				fs.readFiles = map[string][]byte{
-					"/tmp/.slog/config.json": configJSON,
-					"/tmp/test.log":          []byte("[2023-01-01 12:00:00] INFO: old message\n"),
-				}
-			},
-			expectErr:     false,
-			expectedLevel: "WARN",
+			name:          "old newest-first file is migrated before appending",
+			existingData:  older + oldest,
+			expectedOrder: []string{"oldest message", "older message", "new message"},
 		},
 		{
-			name:         "successful prepend to empty file",
-			level:        "info",
-			message:      "first message",
-			existingData: "",
-			setupMocks: func(fs *MockFileSystem) {
-				fs.homeDir = "/tmp"
-				config := Config{
-					LogFile:   "/tmp/test.log",
-					LogLevels: map[string]string{"info": "i"},
-					WriteMode: "prepend",
-				}
-				configJSON, _ := json.Marshal(config)
-				fs.readFiles = map[string][]byte{
-					"/tmp/.slog/config.json": configJSON,
-				}
-				fs.readErr = os.ErrNotExist // File doesn't exist yet
-			},
-			expectErr:     false,
-			expectedLevel: "INFO",
+			name:          "migrated file is appended to",
+			fileOrder:     FileOrderOldestFirst,
+			existingData:  oldest + older,
+			expectedOrder: []string{"oldest message", "older message", "new message"},
 		},
 		{
-			name:         "prepend with read error",
-			level:        "error",
-			message:      "error message",
-			existingData: "",
-			setupMocks: func(fs *MockFileSystem) {
-				fs.homeDir = "/tmp"
-				config := Config{
-					LogFile:   "/tmp/test.log",
-					LogLevels: map[string]string{"error": "e"},
-					WriteMode: "prepend",
-				}
-				configJSON, _ := json.Marshal(config)
-				fs.readFiles = map[string][]byte{
-					"/tmp/.slog/config.json": configJSON,
-				}
-				fs.readErr = errors.New("permission denied")
-			},
-			expectErr: true,
-			errorMsg:  "error reading existing log file",
+			name:          "missing file",
+			setupMocks:    func(fs *MockFileSystem) { fs.readErr = os.ErrNotExist },
+			expectedOrder: []string{"new message"},
+		},
+		{
+			name:       "migration read error",
+			setupMocks: func(fs *MockFileSystem) { fs.readErr = errors.New("permission denied") },
+			expectErr:  true,
+			errorMsg:   "error reading log file",
+		},
+		{
+			name:         "migration rename error keeps existing file",
+			existingData: older + oldest,
+			setupMocks:   func(fs *MockFileSystem) { fs.renameErr = errors.New("input/output error") },
+			expectErr:    true,
+			errorMsg:     "error migrating log file",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			config := Config{
+				LogFile:   "/tmp/test.log",
+				LogLevels: map[string]LevelConfig{"info": {Flag: "i"}},
+				WriteMode: "prepend",
+				FileOrder: tt.fileOrder,
+			}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			if tt.existingData != "" {
+				mockFS.readFiles["/tmp/test.log"] = []byte(tt.existingData)
+			}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockFS)
+			}
 			mockPrinter := &MockPrinter{}
-			tt.setupMocks(mockFS)
 
 			configService := NewConfigService(mockFS, mockPrinter)
 			logService := NewLogService(configService, mockFS, mockPrinter)
 
-			err := logService.AppendLog(tt.level, tt.message)
+			err := logService.AppendLog("info", "new message")
 
 			if tt.expectErr {
 				if err == nil {
@@ -660,14 +849,36 @@ func TestLogService_PrependLog(t *testing.T) {
 				} else if !strings.Contains(err.Error(), tt.errorMsg) {
 					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
 				}
-			} else {
-				if err != nil {
-					t.Errorf("Expected no error, got %v", err)
+				if tt.existingData != "" && string(mockFS.readFiles["/tmp/test.log"]) != tt.existingData {
+					t.Errorf("Expected existing log to be untouched, got %q", string(mockFS.readFiles["/tmp/test.log"]))
 				}
-				if !mockPrinter.ContainsMessage("Logged to") {
-					t.Error("Expected success message about logging")
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !mockPrinter.ContainsMessage("Logged to") {
+				t.Error("Expected success message about logging")
+			}
+
+			lines := strings.Split(strings.TrimSuffix(string(mockFS.writeFiles["/tmp/test.log"]), "\n"), "\n")
+			if len(lines) != len(tt.expectedOrder) {
+				t.Fatalf("Expected %d entries, got %d: %q", len(tt.expectedOrder), len(lines), lines)
+			}
+			for k, message := range tt.expectedOrder {
+				if !strings.HasSuffix(lines[k], ": "+message) {
+					t.Errorf("Expected entry %d to be %q, got %q", k, message, lines[k])
 				}
 			}
+
+			var saved Config
+			if err := json.Unmarshal(mockFS.readFiles["/tmp/.slog/config.json"], &saved); err != nil {
+				t.Fatal(err)
+			}
+			if saved.FileOrder != FileOrderOldestFirst {
+				t.Errorf("Expected config to record file order %q, got %q", FileOrderOldestFirst, saved.FileOrder)
+			}
 		})
 	}
 }
@@ -727,6 +938,491 @@ func TestConfigService_WriteMode(t *testing.T) {
 	}
 }
 
+// Test Format configuration
+func TestConfigService_Format(t *testing.T) {
+	tests := []struct {
+		name           string
+		format         string
+		expectError    bool
+		errorMsg       string
+		expectedFormat string
+	}{
+		{
+			name:           "valid text format",
+			format:         "text",
+			expectedFormat: "text",
+		},
+		{
+			name:           "valid jsonl format",
+			format:         "jsonl",
+			expectedFormat: "jsonl",
+		},
+		{
+			name:        "invalid format",
+			format:      "yaml",
+			expectError: true,
+			errorMsg:    "format must be 'text', 'jsonl' or 'logfmt'",
+		},
+		{
+			name:           "empty format keeps default",
+			format:         "",
+			expectedFormat: "text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockPrinter := &MockPrinter{}
+			mockFS.homeDir = "/tmp"
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			err := configService.UpdateConfig(ConfigUpdate{LogFile: "./test.log", Format: tt.format})
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var config Config
+			if err := json.Unmarshal(mockFS.writeFiles["/tmp/.slog/config.json"], &config); err != nil {
+				t.Fatalf("Failed to unmarshal written config: %v", err)
+			}
+			if config.Format != tt.expectedFormat {
+				t.Errorf("Expected format %q, got %q", tt.expectedFormat, config.Format)
+			}
+		})
+	}
+}
+
+// Test Template configuration
+func TestConfigService_Template(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "valid template",
+			template: "{{.Time}} {{.Host}} [{{.Level}}] {{.Message}}",
+		},
+		{
+			name:        "unsupported placeholder",
+			template:    "{{.Time}} {{.Pid}} {{.Message}}",
+			expectError: true,
+			errorMsg:    "unsupported action",
+		},
+		{
+			name:        "template that cannot be read back",
+			template:    "{{.Time}}{{.Message}}",
+			expectError: true,
+			errorMsg:    "cannot be read back",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockPrinter := &MockPrinter{}
+			mockFS.homeDir = "/tmp"
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			err := configService.UpdateConfig(ConfigUpdate{LogFile: "./test.log", Template: tt.template})
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+				if _, exists := mockFS.writeFiles["/tmp/.slog/config.json"]; exists {
+					t.Error("Expected invalid template not to be saved")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var config Config
+			if err := json.Unmarshal(mockFS.writeFiles["/tmp/.slog/config.json"], &config); err != nil {
+				t.Fatalf("Failed to unmarshal written config: %v", err)
+			}
+			if config.Template != tt.template {
+				t.Errorf("Expected template %q, got %q", tt.template, config.Template)
+			}
+		})
+	}
+}
+
+// Test timestamp configuration
+func TestConfigService_TimeFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      ConfigUpdate
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:   "preset and utc",
+			update: ConfigUpdate{LogFile: "./test.log", TimeFormat: "rfc3339nano", TimeZone: "utc"},
+		},
+		{
+			name:   "named zone",
+			update: ConfigUpdate{LogFile: "./test.log", TimeZone: "Europe/Berlin"},
+		},
+		{
+			name:        "layout that cannot round-trip",
+			update:      ConfigUpdate{LogFile: "./test.log", TimeFormat: "Jan 2 15:04"},
+			expectError: true,
+			errorMsg:    "check the time format",
+		},
+		{
+			name:        "unknown zone",
+			update:      ConfigUpdate{LogFile: "./test.log", TimeZone: "Atlantis/Central"},
+			expectError: true,
+			errorMsg:    "unknown time zone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockPrinter := &MockPrinter{}
+			mockFS.homeDir = "/tmp"
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			err := configService.UpdateConfig(tt.update)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var config Config
+			if err := json.Unmarshal(mockFS.writeFiles["/tmp/.slog/config.json"], &config); err != nil {
+				t.Fatalf("Failed to unmarshal written config: %v", err)
+			}
+			if config.TimeFormat != tt.update.TimeFormat || config.TimeZone != tt.update.TimeZone {
+				t.Errorf("Expected time format %q and zone %q, got %q and %q",
+					tt.update.TimeFormat, tt.update.TimeZone, config.TimeFormat, config.TimeZone)
+			}
+		})
+	}
+}
+
+// Test that entries are written in the configured format
+func TestLogService_AppendLog_JSONL(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	config := Config{LogFile: "/tmp/test.log", WriteMode: "prepend", Format: "jsonl"}
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockFS.readErr = os.ErrNotExist
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	if err := logService.AppendLog("error", "disk full"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var record map[string]string
+	if err := json.Unmarshal(mockFS.writeFiles["/tmp/test.log"], &record); err != nil {
+		t.Fatalf("Expected a JSON entry, got %q: %v", mockFS.writeFiles["/tmp/test.log"], err)
+	}
+	if record["level"] != "ERROR" || record["message"] != "disk full" || record["timestamp"] == "" {
+		t.Errorf("Unexpected entry %v", record)
+	}
+}
+
+// Test that fields are written and can be used to filter the view
+func TestLogService_Fields(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	config := Config{LogFile: "/tmp/test.log", WriteMode: "prepend"}
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockFS.readErr = os.ErrNotExist
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	if err := logService.AppendLogWithFields("error", "deploy failed", map[string]string{"service": "api"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.AppendLogWithFields("info", "deploy started", map[string]string{"service": "web"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(string(mockFS.writeFiles["/tmp/test.log"]), "ERROR service=api: deploy failed") {
+		t.Errorf("Expected fields in entry, got %q", mockFS.writeFiles["/tmp/test.log"])
+	}
+
+	err := logService.AppendLogWithFields("info", "bad", map[string]string{"level": "x"})
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("Expected reserved key error, got %v", err)
+	}
+
+	mockPrinter.Reset()
+	if err := logService.ViewLog(ViewOptions{Quiet: true, Fields: map[string]string{"service": "api"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !mockPrinter.ContainsMessage("deploy failed") {
+		t.Error("Expected matching entry to be shown")
+	}
+	if mockPrinter.ContainsMessage("deploy started") {
+		t.Error("Expected non-matching entry to be hidden")
+	}
+}
+
+// Test logging from a reader such as stdin
+func TestLogService_AppendFromReader(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		perLine         bool
+		expectErr       bool
+		errorMsg        string
+		expectedEntries int
+		expectedMessage string
+	}{
+		{
+			name:            "whole input is one entry",
+			input:           "panic: boom\n\ngoroutine 1 [running]:\n",
+			expectedEntries: 1,
+			expectedMessage: "Logged to /tmp/test.log",
+		},
+		{
+			name:            "one entry per line",
+			input:           "compiling\n\nlinking\r\ndone\n",
+			perLine:         true,
+			expectedEntries: 3,
+			expectedMessage: "Logged 3 entries to /tmp/test.log",
+		},
+		{
+			name:            "invalid UTF-8 lines are skipped",
+			input:           "ok\nbad \xff\n\nafter\n\xfe\n",
+			perLine:         true,
+			errorMsg:        "skipped 2 lines that are not valid UTF-8, the first at line 2",
+			expectedEntries: 2,
+			expectedMessage: "Logged 2 entries to /tmp/test.log",
+		},
+		{
+			name:      "empty input",
+			input:     "\n",
+			expectErr: true,
+		},
+		{
+			name:      "empty input per line",
+			input:     "  \n\n",
+			perLine:   true,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			config := Config{LogFile: "/tmp/test.log", WriteMode: "prepend"}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readErr = os.ErrNotExist
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			err := logService.AppendFromReader("error", strings.NewReader(tt.input), nil, tt.perLine)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			written := string(mockFS.writeFiles["/tmp/test.log"])
+			if count := strings.Count(written, "ERROR"); count != tt.expectedEntries {
+				t.Errorf("Expected %d entries, got %d in %q", tt.expectedEntries, count, written)
+			}
+			if strings.Contains(written, "\r") {
+				t.Errorf("Expected carriage returns to be trimmed, got %q", written)
+			}
+			if !mockPrinter.ContainsMessage(tt.expectedMessage) {
+				t.Errorf("Expected message %q, got %v", tt.expectedMessage, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
+
+// Test that writes hold the log file lock
+func TestLogService_Lock(t *testing.T) {
+	tests := []struct {
+		name      string
+		writeMode string
+		lockErr   error
+		errorMsg  string
+	}{
+		{name: "append mode", writeMode: "append"},
+		{name: "prepend mode", writeMode: "prepend"},
+		{
+			name:      "lock timeout",
+			writeMode: "prepend",
+			lockErr:   fmt.Errorf("%w after 1s", ErrLockTimeout),
+			errorMsg:  "could not lock /tmp/test.log",
+		},
+		{
+			name:      "lock error",
+			writeMode: "append",
+			lockErr:   errors.New("read-only file system"),
+			errorMsg:  "error locking log file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			mockFS.lockErr = tt.lockErr
+			config := Config{LogFile: "/tmp/test.log", WriteMode: tt.writeMode, FileOrder: FileOrderOldestFirst}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readErr = os.ErrNotExist
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			err := logService.AppendLog("info", "test message")
+
+			if tt.errorMsg != "" {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+				if _, written := mockFS.writeFiles["/tmp/test.log"]; written {
+					t.Error("Expected nothing to be written without the lock")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(mockFS.locks) != 1 {
+				t.Fatalf("Expected 1 lock, got %d", len(mockFS.locks))
+			}
+			if mockFS.locks[0].path != "/tmp/test.log.lock" {
+				t.Errorf("Expected lock on /tmp/test.log.lock, got %q", mockFS.locks[0].path)
+			}
+			if !mockFS.locks[0].released {
+				t.Error("Expected lock to be released")
+			}
+		})
+	}
+}
+
+// Test lock timeout configuration
+func TestConfigService_LockTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		lockTimeout string
+		expectError bool
+	}{
+		{name: "seconds", lockTimeout: "30s"},
+		{name: "zero fails immediately", lockTimeout: "0s"},
+		{name: "not a duration", lockTimeout: "soon", expectError: true},
+		{name: "negative", lockTimeout: "-1s", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+
+			configService := NewConfigService(mockFS, &MockPrinter{})
+			err := configService.UpdateConfig(ConfigUpdate{LogFile: "./test.log", LockTimeout: tt.lockTimeout})
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+// Test size rotation configuration
+func TestConfigService_Rotation(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxSize     string
+		maxBackups  int
+		expectError bool
+	}{
+		{name: "megabytes", maxSize: "10MB", maxBackups: 3},
+		{name: "bytes without unit", maxSize: "4096"},
+		{name: "backups only", maxBackups: 2},
+		{name: "unknown unit", maxSize: "10TB", expectError: true},
+		{name: "zero size", maxSize: "0", expectError: true},
+		{name: "negative backups", maxSize: "1M", maxBackups: -1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+
+			configService := NewConfigService(mockFS, &MockPrinter{})
+			err := configService.UpdateConfig(ConfigUpdate{LogFile: "./test.log", MaxSize: tt.maxSize, MaxBackups: tt.maxBackups})
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			config, err := configService.LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.MaxSize != tt.maxSize || config.MaxBackups != tt.maxBackups {
+				t.Errorf("Expected max size %q and %d backups, got %q and %d", tt.maxSize, tt.maxBackups, config.MaxSize, config.MaxBackups)
+			}
+		})
+	}
+}
+
 // Test ViewConfig creates default config when none exists
 func TestConfigService_ViewConfig_CreateDefault(t *testing.T) {
 	mockFS := NewMockFileSystem()
@@ -789,7 +1485,7 @@ func TestApp_HandleConfig(t *testing.T) {
 			mockPrinter := &MockPrinter{}
 
 			if tt.existingConfig {
-				config := Config{LogFile: "/tmp/existing.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/existing.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				mockFS.readData = configJSON
 			}
@@ -832,7 +1528,7 @@ func TestApp_HandleView(t *testing.T) {
 			quiet:          false,
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				fs.readFiles["/tmp/.slog/config.json"] = configJSON
 				fs.readFiles["/tmp/test.log"] = []byte("[2024-01-15 10:30:00] INFO: Test message\n[2024-01-15 10:31:00] WARN: Warning message\n")
@@ -846,7 +1542,7 @@ func TestApp_HandleView(t *testing.T) {
 			quiet:          true,
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				fs.readFiles["/tmp/.slog/config.json"] = configJSON
 				fs.readFiles["/tmp/test.log"] = []byte("[2024-01-15 10:30:00] INFO: Test message\n[2024-01-15 10:31:00] WARN: Warning message\n")
@@ -854,13 +1550,27 @@ func TestApp_HandleView(t *testing.T) {
 			expectError:    false,
 			expectedOutput: "INFO: Test message",
 		},
+		{
+			name:           "view jsonl log file renders text",
+			logFileContent: `{"timestamp":"2024-01-15 10:30:00","level":"INFO","message":"Test message"}` + "\n",
+			quiet:          true,
+			setupMock: func(fs *MockFileSystem) {
+				fs.homeDir = "/tmp"
+				config := Config{LogFile: "/tmp/test.jsonl", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info", Format: "jsonl"}
+				configJSON, _ := json.Marshal(config)
+				fs.readFiles["/tmp/.slog/config.json"] = configJSON
+				fs.readFiles["/tmp/test.jsonl"] = []byte(`{"timestamp":"2024-01-15 10:30:00","level":"INFO","message":"Test message"}` + "\n")
+			},
+			expectError:    false,
+			expectedOutput: "[2024-01-15 10:30:00] INFO: Test message",
+		},
 		{
 			name:           "view empty log file",
 			logFileContent: "",
 			quiet:          false,
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/empty.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/empty.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				fs.readFiles["/tmp/.slog/config.json"] = configJSON
 				fs.readFiles["/tmp/empty.log"] = []byte("")
@@ -874,7 +1584,7 @@ func TestApp_HandleView(t *testing.T) {
 			quiet:          true,
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/empty.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/empty.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				fs.readFiles["/tmp/.slog/config.json"] = configJSON
 				fs.readFiles["/tmp/empty.log"] = []byte("")
@@ -887,7 +1597,7 @@ func TestApp_HandleView(t *testing.T) {
 			quiet: false,
 			setupMock: func(fs *MockFileSystem) {
 				fs.homeDir = "/tmp"
-				config := Config{LogFile: "/tmp/error.log", LogLevels: map[string]string{"info": "i"}, DefaultLevel: "info"}
+				config := Config{LogFile: "/tmp/error.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}, DefaultLevel: "info"}
 				configJSON, _ := json.Marshal(config)
 				fs.readFiles["/tmp/.slog/config.json"] = configJSON
 				// Don't set readFiles for error.log to trigger error
@@ -948,7 +1658,7 @@ func TestApp_HandleConfigView(t *testing.T) {
 				fs.homeDir = "/tmp"
 				config := Config{
 					LogFile:      "/tmp/test.log",
-					LogLevels:    map[string]string{"info": "i", "warn": "w"},
+					LogLevels:    map[string]LevelConfig{"info": {Flag: "i"}, "warn": {Flag: "w"}},
 					DefaultLevel: "info",
 				}
 				configJSON, _ := json.Marshal(config)
@@ -1004,7 +1714,7 @@ func TestApp_HandleConfigView(t *testing.T) {
 func TestApp_HandleLog(t *testing.T) {
 	mockFS := NewMockFileSystem()
 	mockFS.homeDir = "/tmp"
-	config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}}
+	config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}}
 	configJSON, _ := json.Marshal(config)
 	mockFS.readData = configJSON
 	mockPrinter := &MockPrinter{}
@@ -1099,32 +1809,37 @@ func TestParseLevels(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected map[string]string
+		expected map[string]LevelConfig
 	}{
 		{
 			name:     "empty string",
 			input:    "",
-			expected: map[string]string{},
+			expected: map[string]LevelConfig{},
 		},
 		{
 			name:     "single level",
 			input:    "info:i",
-			expected: map[string]string{"info": "i"},
+			expected: map[string]LevelConfig{"info": {Flag: "i"}},
 		},
 		{
 			name:     "multiple levels",
 			input:    "info:i,warn:w,error:e",
-			expected: map[string]string{"info": "i", "warn": "w", "error": "e"},
+			expected: map[string]LevelConfig{"info": {Flag: "i"}, "warn": {Flag: "w"}, "error": {Flag: "e"}},
 		},
 		{
 			name:     "levels with spaces",
 			input:    " info : i , warn : w ",
-			expected: map[string]string{"info": "i", "warn": "w"},
+			expected: map[string]LevelConfig{"info": {Flag: "i"}, "warn": {Flag: "w"}},
 		},
 		{
 			name:     "invalid format",
 			input:    "info,warn:w,invalid",
-			expected: map[string]string{"warn": "w"},
+			expected: map[string]LevelConfig{"warn": {Flag: "w"}},
+		},
+		{
+			name:     "levels with their own files",
+			input:    "info:i,error:e:./errors.log,debug:d:C:\\logs\\debug.log",
+			expected: map[string]LevelConfig{"info": {Flag: "i"}, "error": {Flag: "e", File: "./errors.log"}, "debug": {Flag: "d", File: "C:\\logs\\debug.log"}},
 		},
 	}
 
@@ -1138,7 +1853,104 @@ func TestParseLevels(t *testing.T) {
 
 			for k, v := range tt.expected {
 				if result[k] != v {
-					t.Errorf("Expected level %q:%q, got %q:%q", k, v, k, result[k])
+					t.Errorf("Expected level %q:%+v, got %q:%+v", k, v, k, result[k])
+				}
+			}
+		})
+	}
+}
+
+// Test parseLogArgs function
+func TestParseLogArgs(t *testing.T) {
+	levels := map[string]LevelConfig{"info": {Flag: "i"}, "error": {Flag: "e"}}
+
+	tests := []struct {
+		name            string
+		args            []string
+		expectedLevel   string
+		expectedMessage string
+		expectedFields  map[string]string
+		expectedPerLine bool
+		errorMsg        string
+	}{
+		{
+			name:            "message only",
+			args:            []string{"Application", "started"},
+			expectedMessage: "Application started",
+		},
+		{
+			name:          "level flag without message",
+			args:          []string{"-e"},
+			expectedLevel: "error",
+		},
+		{
+			name:            "per-line flag",
+			args:            []string{"-e", "--per-line"},
+			expectedLevel:   "error",
+			expectedPerLine: true,
+		},
+		{
+			name:            "short level flag",
+			args:            []string{"-e", "deploy failed"},
+			expectedLevel:   "error",
+			expectedMessage: "deploy failed",
+		},
+		{
+			name:            "field flags",
+			args:            []string{"-e", "deploy failed", "--field", "service=api", "--field=region=eu"},
+			expectedLevel:   "error",
+			expectedMessage: "deploy failed",
+			expectedFields:  map[string]string{"service": "api", "region": "eu"},
+		},
+		{
+			name:            "fields after separator",
+			args:            []string{"--error", "deploy", "failed", "--", "service=api", "url=http://x/?a=b"},
+			expectedLevel:   "error",
+			expectedMessage: "deploy failed",
+			expectedFields:  map[string]string{"service": "api", "url": "http://x/?a=b"},
+		},
+		{
+			name:     "field flag without value",
+			args:     []string{"-e", "deploy failed", "--field"},
+			errorMsg: "--field requires a key=value argument",
+		},
+		{
+			name:     "token after separator without equals",
+			args:     []string{"-e", "deploy failed", "--", "service"},
+			errorMsg: "expected key=value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := parseLogArgs(tt.args, levels)
+
+			if tt.errorMsg != "" {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if args.Level != tt.expectedLevel {
+				t.Errorf("Expected level %q, got %q", tt.expectedLevel, args.Level)
+			}
+			if args.Message != tt.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tt.expectedMessage, args.Message)
+			}
+			if args.PerLine != tt.expectedPerLine {
+				t.Errorf("Expected per-line %v, got %v", tt.expectedPerLine, args.PerLine)
+			}
+			if len(args.Fields) != len(tt.expectedFields) {
+				t.Errorf("Expected %d fields, got %d", len(tt.expectedFields), len(args.Fields))
+			}
+			for k, v := range tt.expectedFields {
+				if args.Fields[k] != v {
+					t.Errorf("Expected field %q=%q, got %q", k, v, args.Fields[k])
 				}
 			}
 		})
@@ -1231,7 +2043,7 @@ func BenchmarkParseLevels(b *testing.B) {
 func BenchmarkConfigService_LoadConfig(b *testing.B) {
 	mockFS := NewMockFileSystem()
 	mockFS.homeDir = "/tmp"
-	config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]string{"info": "i"}}
+	config := Config{LogFile: "/tmp/test.log", LogLevels: map[string]LevelConfig{"info": {Flag: "i"}}}
 	configJSON, _ := json.Marshal(config)
 	mockFS.readData = configJSON
 
@@ -1245,3 +2057,56 @@ func BenchmarkConfigService_LoadConfig(b *testing.B) {
 		}
 	}
 }
+
+// newPrependBenchmark sets up a prepend-mode log that already holds the
+// given number of entries.
+func newPrependBenchmark(b *testing.B, entries int) (*LogService, *MockPrinter) {
+	b.Helper()
+
+	fs := NewTempHomeFileSystem(b)
+	logFile := filepath.Join(fs.home, "app.log")
+	line := "[2023-01-01 12:00:00] INFO: existing entry\n"
+	if err := os.WriteFile(logFile, []byte(strings.Repeat(line, entries)), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	printer := &MockPrinter{}
+	configService := NewConfigService(fs, printer)
+	if err := configService.UpdateConfig(ConfigUpdate{LogFile: logFile, WriteMode: "prepend"}); err != nil {
+		b.Fatal(err)
+	}
+	printer.Reset()
+	return NewLogService(configService, fs, printer), printer
+}
+
+func BenchmarkLogService_AppendLog_Prepend(b *testing.B) {
+	for _, entries := range []int{1000, 100000} {
+		b.Run(fmt.Sprintf("%d_entries", entries), func(b *testing.B) {
+			logService, printer := newPrependBenchmark(b, entries)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := logService.AppendLog("info", "benchmark entry"); err != nil {
+					b.Fatal(err)
+				}
+				printer.Reset()
+			}
+		})
+	}
+}
+
+func BenchmarkLogService_ViewLog_Prepend(b *testing.B) {
+	for _, entries := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%d_entries", entries), func(b *testing.B) {
+			logService, printer := newPrependBenchmark(b, entries)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := logService.ViewLog(ViewOptions{Quiet: true}); err != nil {
+					b.Fatal(err)
+				}
+				printer.Reset()
+			}
+		})
+	}
+}
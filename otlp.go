@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// OTLP encodings of exported logs.
+const (
+	OTLPProtobuf = "protobuf"
+	OTLPJSON     = "json"
+)
+
+// otlpLogsPath is appended to an OTLP sink URL without a path, following
+// the OTLP/HTTP convention for a collector's base address.
+const otlpLogsPath = "/v1/logs"
+
+// defaultOTLPService is the service.name of entries when the sink does
+// not set one.
+const defaultOTLPService = "slog"
+
+// validateOTLPSink checks the settings of an OTLP sink.
+func validateOTLPSink(s SinkConfig) error {
+	if err := validateHTTPSink(s); err != nil {
+		return err
+	}
+	if s.Encoding != "" && s.Encoding != OTLPProtobuf && s.Encoding != OTLPJSON {
+		return fmt.Errorf("invalid OTLP encoding %q: use '%s' or '%s'", s.Encoding, OTLPProtobuf, OTLPJSON)
+	}
+	return nil
+}
+
+// otlpEndpoint returns the URL logs are exported to: the sink's URL, with
+// /v1/logs added when it has no path.
+func otlpEndpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Path != "" && u.Path != "/") {
+		return rawURL
+	}
+	u.Path = otlpLogsPath
+	return u.String()
+}
+
+// otlpSeverity returns the OTLP severity number of entries at level. The
+// level's syslog severity picks the range, and TRACE, INFO2 and the FATAL
+// steps keep syslog's finer grades apart. Levels without a severity are
+// exported as INFO, as they are sent to syslog.
+func otlpSeverity(config *Config, level string) int {
+	if strings.EqualFold(level, "trace") {
+		return 1 // TRACE
+	}
+	severity, ok := config.severity(level)
+	if !ok {
+		severity = levelSeverities["info"]
+	}
+	return [...]int{
+		0: 23, // emergency: FATAL3
+		1: 22, // alert: FATAL2
+		2: 21, // critical: FATAL
+		3: 17, // error: ERROR
+		4: 13, // warning: WARN
+		5: 10, // notice: INFO2
+		6: 9,  // info: INFO
+		7: 5,  // debug: DEBUG
+	}[severity]
+}
+
+// The types below mirror the OTLP logs data model. Their JSON encoding is
+// OTLP/JSON; appendProto writes the protobuf encoding by hand, as only
+// strings, timestamps and enums are needed.
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         uint64         `json:"timeUnixNano,string"`
+	ObservedTimeUnixNano uint64         `json:"observedTimeUnixNano,string"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpRequest builds the export request for a batch. Entries are grouped
+// by host, each host being one resource with host.name and service.name
+// attributes; fields become log record attributes.
+func otlpRequest(config *Config, entries []Entry, service string) otlpLogsRequest {
+	var request otlpLogsRequest
+	for _, entry := range entries {
+		var logs *otlpResourceLogs
+		for i := range request.ResourceLogs {
+			if request.ResourceLogs[i].Resource.Attributes[0].Value.StringValue == entry.Host {
+				logs = &request.ResourceLogs[i]
+				break
+			}
+		}
+		if logs == nil {
+			request.ResourceLogs = append(request.ResourceLogs, otlpResourceLogs{
+				Resource: otlpResource{Attributes: []otlpKeyValue{
+					{Key: "host.name", Value: otlpAnyValue{StringValue: entry.Host}},
+					{Key: "service.name", Value: otlpAnyValue{StringValue: service}},
+				}},
+				ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: "slog", Version: version}}},
+			})
+			logs = &request.ResourceLogs[len(request.ResourceLogs)-1]
+		}
+
+		keys := make([]string, 0, len(entry.Fields))
+		for key := range entry.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var attributes []otlpKeyValue
+		for _, key := range keys {
+			attributes = append(attributes, otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: entry.Fields[key]}})
+		}
+
+		stamp := uint64(entry.Time.UnixNano())
+		logs.ScopeLogs[0].LogRecords = append(logs.ScopeLogs[0].LogRecords, otlpLogRecord{
+			TimeUnixNano:         stamp,
+			ObservedTimeUnixNano: stamp,
+			SeverityNumber:       otlpSeverity(config, entry.Level),
+			SeverityText:         entry.Level,
+			Body:                 otlpAnyValue{StringValue: entry.Message},
+			Attributes:           attributes,
+		})
+	}
+	return request
+}
+
+// Protobuf wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+func appendProtoTag(buf []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(field)<<3|uint64(wireType))
+}
+
+func appendProtoString(buf []byte, field int, value string) []byte {
+	if value == "" {
+		return buf
+	}
+	buf = appendProtoTag(buf, field, protoBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// appendProtoMessage writes a nested message, which message appends to
+// the buffer it is given.
+func appendProtoMessage(buf []byte, field int, message func([]byte) []byte) []byte {
+	encoded := message(nil)
+	buf = appendProtoTag(buf, field, protoBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(encoded)))
+	return append(buf, encoded...)
+}
+
+func (r otlpLogsRequest) appendProto(buf []byte) []byte {
+	for _, logs := range r.ResourceLogs {
+		buf = appendProtoMessage(buf, 1, logs.appendProto)
+	}
+	return buf
+}
+
+func (l otlpResourceLogs) appendProto(buf []byte) []byte {
+	buf = appendProtoMessage(buf, 1, l.Resource.appendProto)
+	for _, scope := range l.ScopeLogs {
+		buf = appendProtoMessage(buf, 2, scope.appendProto)
+	}
+	return buf
+}
+
+func (r otlpResource) appendProto(buf []byte) []byte {
+	for _, attribute := range r.Attributes {
+		buf = appendProtoMessage(buf, 1, attribute.appendProto)
+	}
+	return buf
+}
+
+func (s otlpScopeLogs) appendProto(buf []byte) []byte {
+	buf = appendProtoMessage(buf, 1, s.Scope.appendProto)
+	for _, record := range s.LogRecords {
+		buf = appendProtoMessage(buf, 2, record.appendProto)
+	}
+	return buf
+}
+
+func (s otlpScope) appendProto(buf []byte) []byte {
+	buf = appendProtoString(buf, 1, s.Name)
+	return appendProtoString(buf, 2, s.Version)
+}
+
+func (r otlpLogRecord) appendProto(buf []byte) []byte {
+	buf = appendProtoTag(buf, 1, protoFixed64)
+	buf = binary.LittleEndian.AppendUint64(buf, r.TimeUnixNano)
+	buf = appendProtoTag(buf, 2, protoVarint)
+	buf = binary.AppendUvarint(buf, uint64(r.SeverityNumber))
+	buf = appendProtoString(buf, 3, r.SeverityText)
+	buf = appendProtoMessage(buf, 5, r.Body.appendProto)
+	for _, attribute := range r.Attributes {
+		buf = appendProtoMessage(buf, 6, attribute.appendProto)
+	}
+	buf = appendProtoTag(buf, 11, protoFixed64)
+	return binary.LittleEndian.AppendUint64(buf, r.ObservedTimeUnixNano)
+}
+
+func (kv otlpKeyValue) appendProto(buf []byte) []byte {
+	buf = appendProtoString(buf, 1, kv.Key)
+	return appendProtoMessage(buf, 2, kv.Value.appendProto)
+}
+
+func (v otlpAnyValue) appendProto(buf []byte) []byte {
+	// An empty string is still a string value, so it is written anyway
+	buf = appendProtoTag(buf, 1, protoBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(v.StringValue)))
+	return append(buf, v.StringValue...)
+}
+
+// otlpEncoder returns the encoder of an OTLP sink.
+func otlpEncoder(config *Config, sink SinkConfig) httpEncoder {
+	service := sink.Service
+	if service == "" {
+		service = defaultOTLPService
+	}
+	return func(entries []Entry) ([]byte, string, error) {
+		request := otlpRequest(config, entries, service)
+		if sink.Encoding == OTLPJSON {
+			body, err := json.Marshal(request)
+			return body, "application/json", err
+		}
+		return request.appendProto(nil), "application/x-protobuf", nil
+	}
+}
+
+// newOTLPSink prepares a sink that exports entries to an OTLP/HTTP
+// collector.
+func newOTLPSink(fs FileSystem, config *Config, sink SinkConfig) (*httpSink, error) {
+	endpoint := sink
+	endpoint.URL = otlpEndpoint(sink.URL)
+	return newHTTPSink(fs, endpoint, "otlp "+endpoint.URL, otlpEncoder(config, sink), false)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPSeverity(t *testing.T) {
+	config := &Config{LogLevels: map[string]LevelConfig{
+		"success": {Flag: "s", Severity: "notice"},
+		"audit":   {Flag: "a"},
+	}}
+
+	tests := []struct {
+		level    string
+		expected int
+	}{
+		{level: "TRACE", expected: 1},
+		{level: "DEBUG", expected: 5},
+		{level: "INFO", expected: 9},
+		{level: "SUCCESS", expected: 10},
+		{level: "WARN", expected: 13},
+		{level: "ERROR", expected: 17},
+		{level: "FATAL", expected: 21},
+		{level: "ALERT", expected: 22},
+		{level: "EMERGENCY", expected: 23},
+		{level: "AUDIT", expected: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			if got := otlpSeverity(config, tt.level); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOTLPEndpoint(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "http://collector:4318", expected: "http://collector:4318/v1/logs"},
+		{url: "http://collector:4318/", expected: "http://collector:4318/v1/logs"},
+		{url: "https://otlp.example.com/otlp/v1/logs", expected: "https://otlp.example.com/otlp/v1/logs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := otlpEndpoint(tt.url); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOTLPEncoder_JSON(t *testing.T) {
+	stamp := time.Unix(1705314600, 123456789)
+	entries := []Entry{
+		{Time: stamp, Level: "ERROR", Message: "Deploy failed", Host: "web-1", Fields: map[string]string{"service": "api", "attempt": "2"}},
+		{Time: stamp, Level: "INFO", Message: "Deploy retried", Host: "web-2"},
+	}
+
+	encode := otlpEncoder(&Config{}, SinkConfig{Type: SinkOTLP, Encoding: OTLPJSON, Service: "deployer"})
+	body, contentType, err := encode(entries)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Expected application/json, got %q", contentType)
+	}
+
+	resource := func(host string) string {
+		return `"resource":{"attributes":[{"key":"host.name","value":{"stringValue":"` + host + `"}},` +
+			`{"key":"service.name","value":{"stringValue":"deployer"}}]},` +
+			`"scopeLogs":[{"scope":{"name":"slog","version":"` + version + `"},"logRecords":[`
+	}
+	expected := `{"resourceLogs":[{` + resource("web-1") +
+		`{"timeUnixNano":"1705314600123456789","observedTimeUnixNano":"1705314600123456789","severityNumber":17,"severityText":"ERROR",` +
+		`"body":{"stringValue":"Deploy failed"},"attributes":[{"key":"attempt","value":{"stringValue":"2"}},{"key":"service","value":{"stringValue":"api"}}]}]}]},{` +
+		resource("web-2") +
+		`{"timeUnixNano":"1705314600123456789","observedTimeUnixNano":"1705314600123456789","severityNumber":9,"severityText":"INFO",` +
+		`"body":{"stringValue":"Deploy retried"}}]}]}]}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+// protoField is one decoded protobuf field: a number for varint and
+// fixed64 fields, bytes for length-delimited ones.
+type protoField struct {
+	number int
+	value  uint64
+	data   []byte
+}
+
+// decodeProto splits an encoded message into its fields.
+func decodeProto(t *testing.T, data []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("Expected a field tag, got %x", data)
+		}
+		data = data[n:]
+		field := protoField{number: int(tag >> 3)}
+		switch tag & 7 {
+		case protoVarint:
+			field.value, n = binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatalf("Expected a varint in field %d", field.number)
+			}
+			data = data[n:]
+		case protoFixed64:
+			if len(data) < 8 {
+				t.Fatalf("Expected 8 bytes in field %d", field.number)
+			}
+			field.value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protoBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				t.Fatalf("Expected %d bytes in field %d", size, field.number)
+			}
+			field.data = data[n : n+int(size)]
+			data = data[n+int(size):]
+		default:
+			t.Fatalf("Unexpected wire type %d in field %d", tag&7, field.number)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// protoGet returns the fields numbered number.
+func protoGet(fields []protoField, number int) []protoField {
+	var found []protoField
+	for _, field := range fields {
+		if field.number == number {
+			found = append(found, field)
+		}
+	}
+	return found
+}
+
+// protoKeyValues decodes repeated KeyValue fields with string values.
+func protoKeyValues(t *testing.T, fields []protoField) map[string]string {
+	t.Helper()
+	values := make(map[string]string)
+	for _, field := range fields {
+		kv := decodeProto(t, field.data)
+		value := decodeProto(t, protoGet(kv, 2)[0].data)
+		values[string(protoGet(kv, 1)[0].data)] = string(protoGet(value, 1)[0].data)
+	}
+	return values
+}
+
+func TestOTLPEncoder_Protobuf(t *testing.T) {
+	stamp := time.Unix(1705314600, 123456789)
+	entry := Entry{Time: stamp, Level: "WARN", Message: "Disk almost full", Host: "db-1", Fields: map[string]string{"disk": "/dev/sda1"}}
+
+	encode := otlpEncoder(&Config{}, SinkConfig{Type: SinkOTLP})
+	body, contentType, err := encode([]Entry{entry})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != "application/x-protobuf" {
+		t.Errorf("Expected application/x-protobuf, got %q", contentType)
+	}
+
+	resourceLogs := protoGet(decodeProto(t, body), 1)
+	if len(resourceLogs) != 1 {
+		t.Fatalf("Expected one ResourceLogs, got %d", len(resourceLogs))
+	}
+	logs := decodeProto(t, resourceLogs[0].data)
+	resource := decodeProto(t, protoGet(logs, 1)[0].data)
+	attributes := protoKeyValues(t, protoGet(resource, 1))
+	if attributes["host.name"] != "db-1" || attributes["service.name"] != "slog" {
+		t.Errorf("Expected host.name and service.name resource attributes, got %v", attributes)
+	}
+
+	scopeLogs := decodeProto(t, protoGet(logs, 2)[0].data)
+	scope := decodeProto(t, protoGet(scopeLogs, 1)[0].data)
+	if got := string(protoGet(scope, 1)[0].data); got != "slog" {
+		t.Errorf("Expected scope slog, got %q", got)
+	}
+	record := decodeProto(t, protoGet(scopeLogs, 2)[0].data)
+
+	if got := protoGet(record, 1)[0].value; got != uint64(stamp.UnixNano()) {
+		t.Errorf("Expected time %d, got %d", stamp.UnixNano(), got)
+	}
+	if got := protoGet(record, 11)[0].value; got != uint64(stamp.UnixNano()) {
+		t.Errorf("Expected observed time %d, got %d", stamp.UnixNano(), got)
+	}
+	if got := protoGet(record, 2)[0].value; got != 13 {
+		t.Errorf("Expected severity number 13, got %d", got)
+	}
+	if got := string(protoGet(record, 3)[0].data); got != "WARN" {
+		t.Errorf("Expected severity text WARN, got %q", got)
+	}
+	message := decodeProto(t, protoGet(record, 5)[0].data)
+	if got := string(protoGet(message, 1)[0].data); got != "Disk almost full" {
+		t.Errorf("Expected the message as body, got %q", got)
+	}
+	if got := protoKeyValues(t, protoGet(record, 6)); len(got) != 1 || got["disk"] != "/dev/sda1" {
+		t.Errorf("Expected the fields as attributes, got %v", got)
+	}
+}
+
+func TestLogService_OTLPSink(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	config := Config{
+		Sinks: []SinkConfig{
+			{Path: "/logs/app.log"},
+			{Type: SinkOTLP, URL: server.URL, Service: "backup", Headers: map[string]string{"X-Scope-OrgID": "ops"}, MinLevel: "warn"},
+		},
+		FileOrder: FileOrderOldestFirst,
+	}
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	if err := logService.AppendLog("info", "below the minimum level"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.AppendLogWithFields("error", "Backup failed", map[string]string{"volume": "data"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.Close(); err != nil {
+		t.Fatalf("Expected no error closing sinks, got %v", err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected one export request, got %d", len(requests))
+	}
+	request := requests[0]
+	if request.URL.Path != "/v1/logs" || request.Header.Get("Content-Type") != "application/x-protobuf" || request.Header.Get("X-Scope-OrgID") != "ops" {
+		t.Errorf("Expected a protobuf POST to /v1/logs with the configured header, got %s %v", request.URL.Path, request.Header)
+	}
+	logs := decodeProto(t, protoGet(decodeProto(t, bodies[0]), 1)[0].data)
+	scopeLogs := decodeProto(t, protoGet(logs, 2)[0].data)
+	records := protoGet(scopeLogs, 2)
+	if len(records) != 1 {
+		t.Fatalf("Expected one log record, got %d", len(records))
+	}
+	record := decodeProto(t, records[0].data)
+	if got := string(protoGet(decodeProto(t, protoGet(record, 5)[0].data), 1)[0].data); got != "Backup failed" {
+		t.Errorf("Expected the error entry, got %q", got)
+	}
+	resource := protoKeyValues(t, protoGet(decodeProto(t, protoGet(logs, 1)[0].data), 1))
+	if resource["service.name"] != "backup" {
+		t.Errorf("Expected service.name backup, got %v", resource)
+	}
+	if !mockPrinter.ContainsMessage("Logged to /logs/app.log, otlp " + server.URL + "/v1/logs") {
+		t.Errorf("Expected output naming both sinks, got %v", mockPrinter.GetMessages())
+	}
+}
+
+func TestConfigService_OTLPSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		sink     SinkConfig
+		errorMsg string
+	}{
+		{name: "collector", sink: SinkConfig{Type: SinkOTLP, URL: "http://collector:4318"}},
+		{name: "json", sink: SinkConfig{Type: SinkOTLP, URL: "http://collector:4318", Encoding: OTLPJSON, Service: "cron"}},
+		{name: "unknown encoding", sink: SinkConfig{Type: SinkOTLP, URL: "http://collector:4318", Encoding: "grpc"}, errorMsg: "invalid OTLP encoding"},
+		{name: "missing url", sink: SinkConfig{Type: SinkOTLP}, errorMsg: "requires a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configService := NewConfigService(mockFS, &MockPrinter{})
+
+			err := configService.UpdateConfig(ConfigUpdate{AddSinks: []SinkConfig{tt.sink}})
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
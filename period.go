@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rotation periods accepted by the rotate option.
+const (
+	RotateDaily  = "daily"
+	RotateHourly = "hourly"
+)
+
+// Suffixes inserted before the extension of a log file name without date
+// tokens when the rotate option is set.
+var rotateSuffixes = map[string]string{
+	RotateDaily:  "-%Y-%m-%d",
+	RotateHourly: "-%Y-%m-%d-%H",
+}
+
+// Digits matched for each date token allowed in a log file name.
+var dateTokenWidths = map[byte]int{'Y': 4, 'm': 2, 'd': 2, 'H': 2}
+
+// periodPattern names one log file per day or hour, such as
+// app-2024-01-15.log for the pattern app-%Y-%m-%d.log.
+type periodPattern struct {
+	dir      string
+	parts    []namePart
+	regex    *regexp.Regexp
+	tokens   []byte // token letters in the order of the regex groups
+	location *time.Location
+}
+
+// newPeriodPattern returns the pattern for the config's log file, or nil
+// when the log file is not rotated by time. Date tokens in the file name
+// take precedence over the rotate option; 'slog config' does not set both.
+func newPeriodPattern(config *Config) (*periodPattern, error) {
+	dir, name := filepath.Split(config.LogFile)
+	if containsDateToken(dir) {
+		return nil, fmt.Errorf("date tokens are only supported in the log file name, not in %q", dir)
+	}
+
+	if !containsDateToken(name) {
+		if config.Rotate == "" {
+			return nil, nil
+		}
+		suffix, ok := rotateSuffixes[config.Rotate]
+		if !ok {
+			return nil, fmt.Errorf("rotate must be '%s' or '%s'", RotateDaily, RotateHourly)
+		}
+		// The name is used literally, so percent signs in it are escaped
+		ext := filepath.Ext(name)
+		name = strings.ReplaceAll(strings.TrimSuffix(name, ext), "%", "%%") + suffix + strings.ReplaceAll(ext, "%", "%%")
+	}
+
+	parts, err := splitDateTokens(name)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := loadTimeZone(config.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := &periodPattern{dir: filepath.Clean(dir), parts: parts, location: location}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, part := range parts {
+		if part.token != 0 {
+			pattern.tokens = append(pattern.tokens, part.token)
+			expr.WriteString(fmt.Sprintf("([0-9]{%d})", dateTokenWidths[part.token]))
+			continue
+		}
+		expr.WriteString(regexp.QuoteMeta(part.text))
+	}
+	expr.WriteString("(" + regexp.QuoteMeta(compressedExt) + ")?$")
+	pattern.regex = regexp.MustCompile(expr.String())
+	return pattern, nil
+}
+
+// namePart is either literal text or a date token such as 'Y' for %Y.
+type namePart struct {
+	text  string
+	token byte
+}
+
+// splitDateTokens splits a file name into literal text and date tokens.
+// "%%" stands for a literal percent sign.
+func splitDateTokens(name string) ([]namePart, error) {
+	var parts []namePart
+	var literal strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			literal.WriteByte(name[i])
+			continue
+		}
+		if i+1 == len(name) {
+			return nil, fmt.Errorf("log file name %q ends with a lone %%", name)
+		}
+		i++
+		if name[i] == '%' {
+			literal.WriteByte('%')
+			continue
+		}
+		if _, ok := dateTokenWidths[name[i]]; !ok {
+			return nil, fmt.Errorf("unknown date token %%%c in log file name; use %%Y, %%m, %%d or %%H", name[i])
+		}
+		if literal.Len() > 0 {
+			parts = append(parts, namePart{text: literal.String()})
+			literal.Reset()
+		}
+		parts = append(parts, namePart{token: name[i]})
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, namePart{text: literal.String()})
+	}
+	return parts, nil
+}
+
+// containsDateToken reports whether a file name contains a date token. A
+// name without one is a plain file name, percent signs included.
+func containsDateToken(name string) bool {
+	for i := 0; i+1 < len(name); i++ {
+		if name[i] != '%' {
+			continue
+		}
+		if name[i+1] == '%' {
+			i++
+			continue
+		}
+		if _, ok := dateTokenWidths[name[i+1]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the log file for the period containing t.
+func (p *periodPattern) path(t time.Time) string {
+	t = t.In(p.location)
+	var name strings.Builder
+	for _, part := range p.parts {
+		switch part.token {
+		case 'Y':
+			name.WriteString(fmt.Sprintf("%04d", t.Year()))
+		case 'm':
+			name.WriteString(fmt.Sprintf("%02d", int(t.Month())))
+		case 'd':
+			name.WriteString(fmt.Sprintf("%02d", t.Day()))
+		case 'H':
+			name.WriteString(fmt.Sprintf("%02d", t.Hour()))
+		default:
+			name.WriteString(part.text)
+		}
+	}
+	return filepath.Join(p.dir, name.String())
+}
+
+// files returns the existing period files, oldest period first. A
+// compressed file follows the plain file of the same period, if any.
+func (p *periodPattern) files(fs FileSystem) ([]string, error) {
+	entries, err := fs.ReadDir(p.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing log files: %w", err)
+	}
+
+	type periodFile struct {
+		path  string
+		start time.Time
+	}
+	var found []periodFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := p.regex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		found = append(found, periodFile{
+			path:  filepath.Join(p.dir, entry.Name()),
+			start: p.periodStart(match[1 : len(match)-1]),
+		})
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if !found[i].start.Equal(found[j].start) {
+			return found[i].start.Before(found[j].start)
+		}
+		return found[i].path < found[j].path
+	})
+
+	paths := make([]string, len(found))
+	for i, file := range found {
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+// periodStart builds the start of the period from the values matched for
+// each token in a file name.
+func (p *periodPattern) periodStart(values []string) time.Time {
+	year, month, day, hour := 1, 1, 1, 0
+	for i, token := range p.tokens {
+		n, _ := strconv.Atoi(values[i])
+		switch token {
+		case 'Y':
+			year = n
+		case 'm':
+			month = n
+		case 'd':
+			day = n
+		case 'H':
+			hour = n
+		}
+	}
+	return time.Date(year, time.Month(month), day, hour, 0, 0, 0, p.location)
+}
+
+// logFileAt returns the file an entry written at t goes to.
+func (c *Config) logFileAt(t time.Time) (string, error) {
+	pattern, err := newPeriodPattern(c)
+	if err != nil || pattern == nil {
+		return c.LogFile, err
+	}
+	return pattern.path(t), nil
+}
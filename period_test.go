@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPeriodPattern(t *testing.T) {
+	moment := time.Date(2024, 1, 5, 7, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		logFile  string
+		rotate   string
+		zone     string
+		expected string // empty when the log is not rotated by time
+		errorMsg string
+	}{
+		{name: "no rotation", logFile: "/logs/app.log", expected: ""},
+		{name: "daily tokens", logFile: "/logs/app-%Y-%m-%d.log", expected: "/logs/app-2024-01-05.log"},
+		{name: "hourly tokens", logFile: "/logs/app-%Y%m%d%H.log", expected: "/logs/app-2024010507.log"},
+		{name: "tokens in any order", logFile: "/logs/%d.%m.%Y-app.log", expected: "/logs/05.01.2024-app.log"},
+		{name: "escaped percent", logFile: "/logs/100%%-%Y.log", expected: "/logs/100%-2024.log"},
+		{name: "rotate daily", logFile: "/logs/app.log", rotate: "daily", expected: "/logs/app-2024-01-05.log"},
+		{name: "rotate hourly", logFile: "/logs/app.log", rotate: "hourly", expected: "/logs/app-2024-01-05-07.log"},
+		{name: "rotate without extension", logFile: "/logs/app", rotate: "daily", expected: "/logs/app-2024-01-05"},
+		{name: "percent signs without tokens are literal", logFile: "/logs/50%.log", expected: ""},
+		{name: "rotate keeps percent signs literal", logFile: "/logs/50%.log", rotate: "daily", expected: "/logs/50%-2024-01-05.log"},
+		{name: "tokens win over rotate", logFile: "/logs/app-%Y.log", rotate: "hourly", expected: "/logs/app-2024.log"},
+		{name: "period in configured zone", logFile: "/logs/app-%Y-%m-%d-%H.log", zone: "Asia/Tokyo", expected: "/logs/app-2024-01-05-16.log"},
+		{name: "tokens in directory", logFile: "/logs/%Y/app.log", errorMsg: "only supported in the log file name"},
+		{name: "unknown token", logFile: "/logs/app-%Y-%F.log", errorMsg: "unknown date token %F"},
+		{name: "lone percent", logFile: "/logs/app-%Y-%", errorMsg: "lone %"},
+		{name: "unknown rotate", logFile: "/logs/app.log", rotate: "weekly", errorMsg: "rotate must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := newPeriodPattern(&Config{LogFile: tt.logFile, Rotate: tt.rotate, TimeZone: tt.zone})
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.expected == "" {
+				if pattern != nil {
+					t.Errorf("Expected no time rotation, got %q", pattern.path(moment))
+				}
+				return
+			}
+			if pattern == nil {
+				t.Fatalf("Expected time rotation to %q, got none", tt.expected)
+			}
+			if got := pattern.path(moment); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPeriodPattern_Files(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	for _, name := range []string{
+		"/logs/01.02.2024-app.log",
+		"/logs/15.01.2024-app.log",
+		"/logs/31.12.2023-app.log",
+		"/logs/15.01.2024-app.log.1", // size-rotated copy
+		"/logs/app.log",
+		"/logs/1.1.2024-app.log",
+		"/other/02.01.2024-app.log",
+	} {
+		mockFS.readFiles[name] = []byte("entry\n")
+	}
+
+	pattern, err := newPeriodPattern(&Config{LogFile: "/logs/%d.%m.%Y-app.log"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	files, err := pattern.files(mockFS)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"/logs/31.12.2023-app.log", "/logs/15.01.2024-app.log", "/logs/01.02.2024-app.log"}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestConfigService_TimeRotation(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    string
+		update      ConfigUpdate
+		expectError bool
+		warning     string
+	}{
+		{name: "dated file name", update: ConfigUpdate{LogFile: "./logs/app-%Y-%m-%d.log"}},
+		{name: "rotate option", update: ConfigUpdate{LogFile: "./app.log", Rotate: "hourly"}},
+		{name: "unknown rotate", update: ConfigUpdate{LogFile: "./app.log", Rotate: "weekly"}, expectError: true},
+		{name: "unknown token", update: ConfigUpdate{LogFile: "./app-%Y-%s.log"}, expectError: true},
+		{name: "rotate with a dated file name", update: ConfigUpdate{LogFile: "./app-%Y.log", Rotate: "daily"}, expectError: true},
+		{name: "rotate with a dated sink", existing: `{"sinks":[{"path":"./app.log"},{"path":"./audit-%Y-%m.log"}]}`, update: ConfigUpdate{Rotate: "daily"}, expectError: true},
+		{
+			name:     "dated file name with rotate stored",
+			existing: `{"log_file":"./app.log","rotate":"daily"}`,
+			update:   ConfigUpdate{LogFile: "./app-%Y-%m-%d-%H.log"},
+			warning:  "./app-%Y-%m-%d-%H.log has date tokens in its name, so rotate 'daily' does not apply to it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			if tt.existing != "" {
+				mockFS.readFiles["/tmp/.slog/config.json"] = []byte(tt.existing)
+			}
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			err := configService.UpdateConfig(tt.update)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if !tt.expectError && !mockPrinter.ContainsMessage("current file") {
+				t.Error("Expected the current period file to be shown")
+			}
+			if tt.warning != "" && !mockPrinter.ContainsMessage(tt.warning) {
+				t.Errorf("Expected warning %q, got %v", tt.warning, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
+
+func TestLogService_TimeRotation(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	config := Config{LogFile: "/logs/app-%Y-%m-%d.log", TimeZone: "utc", FileOrder: FileOrderOldestFirst}
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockPrinter := &MockPrinter{}
+
+	configService := NewConfigService(mockFS, mockPrinter)
+	logService := NewLogService(configService, mockFS, mockPrinter)
+
+	pattern, err := newPeriodPattern(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := pattern.path(time.Now())
+	if err := logService.AppendLog("info", "today"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	after := pattern.path(time.Now())
+
+	written := before
+	if _, exists := mockFS.writeFiles[before]; !exists {
+		written = after
+	}
+	if !strings.Contains(string(mockFS.writeFiles[written]), "INFO: today") {
+		t.Fatalf("Expected entry in %s, got files %v", before, mockFS.writeFiles)
+	}
+	if !mockPrinter.ContainsMessage("Logged to " + written) {
+		t.Errorf("Expected success message naming %s, got %v", written, mockPrinter.GetMessages())
+	}
+	if len(mockFS.locks) != 1 || mockFS.locks[0].path != "/logs/app-%Y-%m-%d.log.lock" {
+		t.Errorf("Expected one lock shared by all periods, got %v", mockFS.locks)
+	}
+
+	// Earlier periods are read first, whatever the order in the directory
+	mockFS.readFiles["/logs/app-2023-12-31.log"] = []byte("[2023-12-31 23:59:00] INFO: last year\n")
+	mockFS.readFiles["/logs/app-2024-01-01.log"] = []byte("[2024-01-01 00:00:01] INFO: new year")
+
+	tests := []struct {
+		writeMode string
+		expected  []string
+	}{
+		{writeMode: "append", expected: []string{"last year", "new year", "today"}},
+		{writeMode: "prepend", expected: []string{"today", "new year", "last year"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.writeMode, func(t *testing.T) {
+			config.WriteMode = tt.writeMode
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockPrinter.Reset()
+
+			if err := logService.ViewLog(ViewOptions{Quiet: true}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			output := strings.Join(mockPrinter.GetMessages(), "")
+			position := -1
+			for _, message := range tt.expected {
+				next := strings.Index(output, message)
+				if next <= position {
+					t.Fatalf("Expected entries in order %v, got %q", tt.expected, output)
+				}
+				position = next
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// FileOrderOldestFirst marks a config whose log file keeps the oldest entry
+// first in both write modes; prepend mode reverses the file when viewing
+// it. Earlier versions stored prepend-mode files newest first, rewriting
+// the whole file on every write, and their configs have no file order.
+const FileOrderOldestFirst = "oldest_first"
+
+// newestFirstOnDisk reports whether the log file still uses the old
+// prepend layout with the newest entry at the top.
+func (c *Config) newestFirstOnDisk() bool {
+	return c.WriteMode == "prepend" && c.FileOrder != FileOrderOldestFirst
+}
+
+// MigratePrependLog rewrites a log file written by the old prepend mode in
+// oldest-first order and records the new order in the config. It does
+// nothing when the log file has already been migrated. Such configs
+// predate sinks, so the file is the main log file.
+func (cs *ConfigService) MigratePrependLog() error {
+	settings, err := cs.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !settings.newestFirstOnDisk() {
+		return nil
+	}
+	config, err := settings.mainLog()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockLogFile(cs.fs, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Close(); err != nil {
+			fmt.Printf("Warning: failed to release log file lock: %v\n", err)
+		}
+	}()
+
+	// Another process may have migrated the file while we waited for the lock
+	settings, err = cs.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !settings.newestFirstOnDisk() {
+		return nil
+	}
+	config, err = settings.mainLog()
+	if err != nil {
+		return err
+	}
+
+	data, err := cs.fs.ReadFile(config.LogFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading log file: %w", err)
+	}
+	if len(data) > 0 {
+		err = writeFileAtomic(cs.fs, config.LogFile, []byte(reverseRecords(string(data))))
+		if err != nil {
+			return fmt.Errorf("error migrating log file to oldest-first order: %w", err)
+		}
+	}
+
+	settings.FileOrder = FileOrderOldestFirst
+	return cs.writeConfig(settings)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReverseRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "empty", input: "", expected: ""},
+		{name: "single record", input: "a\n", expected: "a\n"},
+		{name: "several records", input: "a\nb\nc\n", expected: "c\nb\na\n"},
+		{name: "missing final newline", input: "a\nb", expected: "b\na\n"},
+		{name: "continuation lines stay with their record", input: "a\n  a2\nb\n  b2\n  b3\n", expected: "b\n  b2\n  b3\na\n  a2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reverseRecords(tt.input); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// writeLegacyPrependConfig stores a prepend-mode config as written before
+// log files were kept oldest first, with a newest-first log file.
+func writeLegacyPrependConfig(t *testing.T, fs *TempHomeFileSystem, logData string) string {
+	t.Helper()
+
+	logFile := filepath.Join(fs.home, "app.log")
+	if err := os.WriteFile(logFile, []byte(logData), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configJSON, _ := json.Marshal(Config{LogFile: logFile, WriteMode: "prepend", DefaultLevel: "info"})
+	if err := os.MkdirAll(filepath.Join(fs.home, ".slog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fs.home, ".slog", "config.json"), configJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return logFile
+}
+
+func TestConfigService_MigratePrependLog(t *testing.T) {
+	legacy := "[2023-01-01 12:02:00] ERROR: newest\n  trace line\n" +
+		"[2023-01-01 12:01:00] WARN: middle\n" +
+		"[2023-01-01 12:00:00] INFO: oldest\n"
+	migrated := "[2023-01-01 12:00:00] INFO: oldest\n" +
+		"[2023-01-01 12:01:00] WARN: middle\n" +
+		"[2023-01-01 12:02:00] ERROR: newest\n  trace line\n"
+
+	tests := []struct {
+		name    string
+		trigger func(*ConfigService, *LogService) error
+	}{
+		{
+			name: "on config change",
+			trigger: func(cs *ConfigService, ls *LogService) error {
+				return cs.UpdateConfig(ConfigUpdate{DefaultLevel: "warn"})
+			},
+		},
+		{
+			name: "on write",
+			trigger: func(cs *ConfigService, ls *LogService) error {
+				return ls.AppendLog("info", "latest")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := NewTempHomeFileSystem(t)
+			logFile := writeLegacyPrependConfig(t, fs, legacy)
+			printer := &MockPrinter{}
+			configService := NewConfigService(fs, printer)
+			logService := NewLogService(configService, fs, printer)
+
+			if err := tt.trigger(configService, logService); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			content, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(content), migrated) {
+				t.Errorf("Expected migrated log to start with %q, got %q", migrated, string(content))
+			}
+			info, err := os.Stat(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != 0600 {
+				t.Errorf("Expected mode 0600 to be kept, got %o", got)
+			}
+
+			config, err := configService.LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.FileOrder != FileOrderOldestFirst {
+				t.Errorf("Expected file order %q, got %q", FileOrderOldestFirst, config.FileOrder)
+			}
+
+			// Migrating again must not reverse the file back
+			if err := configService.MigratePrependLog(); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			again, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(content) {
+				t.Errorf("Expected second migration to change nothing, got %q", string(again))
+			}
+
+			printer.Reset()
+			if err := logService.ViewLog(ViewOptions{Quiet: true}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			output := strings.Join(printer.GetMessages(), "\n")
+			if strings.Index(output, "newest") > strings.Index(output, "oldest") {
+				t.Errorf("Expected view to show newest entry first, got %q", output)
+			}
+		})
+	}
+}
+
+func TestLogService_ViewLog_Prepend(t *testing.T) {
+	tests := []struct {
+		name      string
+		writeMode string
+		fileOrder string
+		stored    string
+		expected  string
+	}{
+		{
+			name:      "prepend mode reverses the file",
+			writeMode: "prepend",
+			fileOrder: FileOrderOldestFirst,
+			stored:    "[2023-01-01 12:00:00] INFO: first\n[2023-01-01 12:01:00] INFO: second\n",
+			expected:  "[2023-01-01 12:01:00] INFO: second\n[2023-01-01 12:00:00] INFO: first\n",
+		},
+		{
+			name:      "unmigrated prepend file is shown as stored",
+			writeMode: "prepend",
+			stored:    "[2023-01-01 12:01:00] INFO: second\n[2023-01-01 12:00:00] INFO: first\n",
+			expected:  "[2023-01-01 12:01:00] INFO: second\n[2023-01-01 12:00:00] INFO: first\n",
+		},
+		{
+			name:      "append mode is shown as stored",
+			writeMode: "append",
+			fileOrder: FileOrderOldestFirst,
+			stored:    "[2023-01-01 12:00:00] INFO: first\n[2023-01-01 12:01:00] INFO: second\n",
+			expected:  "[2023-01-01 12:00:00] INFO: first\n[2023-01-01 12:01:00] INFO: second\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(Config{LogFile: "/tmp/test.log", WriteMode: tt.writeMode, FileOrder: tt.fileOrder})
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readFiles["/tmp/test.log"] = []byte(tt.stored)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			if err := logService.ViewLog(ViewOptions{Quiet: true}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := strings.Join(mockPrinter.GetMessages(), ""); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
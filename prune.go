@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy limits how much history 'slog prune' keeps. Zero values
+// mean no limit.
+type RetentionPolicy struct {
+	MaxAge     time.Duration
+	MaxSize    int64
+	MaxEntries int
+}
+
+// Set reports whether the policy limits anything.
+func (p RetentionPolicy) Set() bool {
+	return p.MaxAge > 0 || p.MaxSize > 0 || p.MaxEntries > 0
+}
+
+// parseAge parses a retention age: a Go duration such as "36h", or a
+// whole number of days or weeks such as "30d" or "2w".
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid age %q: use a duration such as '36h', '30d' or '2w'", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid age %q: use a duration such as '36h', '30d' or '2w'", value)
+	}
+	return age, nil
+}
+
+// retentionPolicy returns the configured retention policy.
+func (c *Config) retentionPolicy() (RetentionPolicy, error) {
+	age, err := parseAge(c.RetainAge)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	size, err := parseSize(c.RetainSize)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	if c.RetainEntries < 0 {
+		return RetentionPolicy{}, fmt.Errorf("retained entries must not be negative")
+	}
+	return RetentionPolicy{MaxAge: age, MaxSize: size, MaxEntries: c.RetainEntries}, nil
+}
+
+// PruneOptions controls what 'slog prune' removes. Non-empty limits
+// replace the configured retention policy for this run.
+type PruneOptions struct {
+	DryRun        bool
+	RetainAge     string
+	RetainSize    string
+	RetainEntries int
+}
+
+// prunedRecord is one entry of the log, in the order it was written.
+type prunedRecord struct {
+	segment int
+	text    string
+	time    time.Time // zero when the record could not be parsed
+	dropped bool
+}
+
+// logSegmentRecords are the entries of one file of the log.
+type logSegmentRecords struct {
+	path    string
+	records int
+}
+
+// Prune removes the oldest entries of each file sink's log until it is
+// within the retention policy. Files whose entries are all removed are
+// deleted, and the file holding the oldest kept entry is rewritten without
+// the older ones.
+func (ls *LogService) Prune(options PruneOptions) error {
+	config, err := ls.configService.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	overrides := *config
+	if options.RetainAge != "" {
+		overrides.RetainAge = options.RetainAge
+	}
+	if options.RetainSize != "" {
+		overrides.RetainSize = options.RetainSize
+	}
+	if options.RetainEntries != 0 {
+		overrides.RetainEntries = options.RetainEntries
+	}
+	policy, err := overrides.retentionPolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.Set() {
+		return fmt.Errorf("no retention policy set; use --retain-age, --retain-size or --retain-entries, or set one with 'slog config'")
+	}
+
+	total := 0
+	for _, file := range config.logFiles() {
+		drop, err := ls.pruneLog(file, policy, options.DryRun)
+		if err != nil {
+			return err
+		}
+		total += drop
+	}
+
+	if total == 0 {
+		ls.printer.PrintSuccess("Nothing to prune: the log is within the retention policy")
+		return nil
+	}
+	if options.DryRun {
+		ls.printer.PrintWarning(fmt.Sprintf("Dry run: would remove %d entries; nothing was changed", total))
+	}
+	return nil
+}
+
+// pruneLog prunes the files of one log and returns how many entries were
+// removed, or would be on a dry run.
+func (ls *LogService) pruneLog(config *Config, policy RetentionPolicy, dryRun bool) (int, error) {
+	lock, err := lockLogFile(ls.fs, config)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := lock.Close(); err != nil {
+			fmt.Printf("Warning: failed to release log file lock: %v\n", err)
+		}
+	}()
+
+	parser, err := NewFormatter(config.Format, config.FormatOptions())
+	if err != nil {
+		return 0, err
+	}
+
+	paths, err := logSegments(ls.fs, config)
+	if err != nil {
+		return 0, err
+	}
+
+	// Old prepend-mode files keep the newest entry at the top
+	newestFirst := config.newestFirstOnDisk()
+
+	var segments []logSegmentRecords
+	var records []prunedRecord
+	for i, path := range paths {
+		data, err := readSegment(ls.fs, path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return 0, err
+		}
+		content := string(data)
+		if newestFirst {
+			content = reverseRecords(content)
+		}
+
+		segment := logSegmentRecords{path: path}
+		if content != "" {
+			for _, text := range splitRecords(content) {
+				record := prunedRecord{segment: i, text: text}
+				if entry, err := parser.Parse(text); err == nil {
+					record.time = entry.Time
+				}
+				records = append(records, record)
+				segment.records++
+			}
+		}
+		segments = append(segments, segment)
+	}
+
+	drop := recordsToDrop(records, policy, time.Now())
+	if drop == 0 {
+		return 0, nil
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+
+	dropped := make([]int, len(segments))
+	for i := range records[:drop] {
+		records[i].dropped = true
+		dropped[records[i].segment]++
+	}
+	for i, segment := range segments {
+		if dropped[i] == 0 {
+			continue
+		}
+		if dropped[i] == segment.records {
+			ls.printer.Print(fmt.Sprintf("  %s %s (%d entries)", strings.ToLower(verb), segment.path, dropped[i]))
+		} else {
+			ls.printer.Print(fmt.Sprintf("  %s %d of %d entries from %s", strings.ToLower(verb), dropped[i], segment.records, segment.path))
+		}
+		if dryRun {
+			continue
+		}
+		// The log file itself is emptied rather than removed
+		remove := dropped[i] == segment.records && segment.path != config.LogFile
+		if err := ls.pruneSegment(segment.path, records, i, remove, newestFirst); err != nil {
+			return 0, err
+		}
+	}
+
+	if !dryRun {
+		ls.printer.PrintSuccess(fmt.Sprintf("%s %d entries from %s", verb, drop, config.LogFile))
+	}
+	return drop, nil
+}
+
+// pruneSegment deletes a file of the log, or rewrites it with only the
+// records that are kept.
+func (ls *LogService) pruneSegment(path string, records []prunedRecord, segment int, remove bool, newestFirst bool) error {
+	if remove {
+		if err := ls.fs.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing %s: %w", path, err)
+		}
+		return nil
+	}
+
+	var kept strings.Builder
+	for _, record := range records {
+		if record.segment == segment && !record.dropped {
+			kept.WriteString(record.text + "\n")
+		}
+	}
+	content := kept.String()
+	if newestFirst {
+		content = reverseRecords(content)
+	}
+
+	data := []byte(content)
+	if strings.HasSuffix(path, compressedExt) {
+		var err error
+		data, err = gzipBytes(strings.TrimSuffix(path, compressedExt), time.Now(), data)
+		if err != nil {
+			return fmt.Errorf("error compressing %s: %w", path, err)
+		}
+	}
+	if err := writeFileAtomic(ls.fs, path, data); err != nil {
+		return fmt.Errorf("error rewriting %s: %w", path, err)
+	}
+	return nil
+}
+
+// recordsToDrop returns how many of the oldest records must go for the
+// rest to fit the policy. A record whose time cannot be parsed is dated
+// like the record before it.
+func recordsToDrop(records []prunedRecord, policy RetentionPolicy, now time.Time) int {
+	drop := 0
+
+	if policy.MaxEntries > 0 && len(records) > policy.MaxEntries {
+		drop = len(records) - policy.MaxEntries
+	}
+
+	if policy.MaxSize > 0 {
+		var size int64
+		for _, record := range records {
+			size += int64(len(record.text) + 1)
+		}
+		n := 0
+		for size > policy.MaxSize && n < len(records) {
+			size -= int64(len(records[n].text) + 1)
+			n++
+		}
+		drop = max(drop, n)
+	}
+
+	if policy.MaxAge > 0 {
+		cutoff := now.Add(-policy.MaxAge)
+		n := 0
+		var last time.Time
+		for _, record := range records {
+			if !record.time.IsZero() {
+				last = record.time
+			}
+			if last.IsZero() || !last.Before(cutoff) {
+				break
+			}
+			n++
+		}
+		drop = max(drop, n)
+	}
+
+	return drop
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{input: "", expected: 0},
+		{input: "36h", expected: 36 * time.Hour},
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "30d", expected: 30 * 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "1.5d", expectError: true},
+		{input: "0d", expectError: true},
+		{input: "-1h", expectError: true},
+		{input: "d", expectError: true},
+		{input: "soon", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseAge(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecordsToDrop(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	records := []prunedRecord{
+		{text: "0123456789", time: day(1)},
+		{text: "0123456789"}, // continuation of a record that could not be parsed
+		{text: "0123456789", time: day(10)},
+		{text: "0123456789", time: day(20)},
+		{text: "0123456789", time: day(30)},
+	}
+
+	tests := []struct {
+		name     string
+		policy   RetentionPolicy
+		expected int
+	}{
+		{name: "within every limit", policy: RetentionPolicy{MaxEntries: 10, MaxSize: 1000, MaxAge: 365 * 24 * time.Hour}, expected: 0},
+		{name: "entries", policy: RetentionPolicy{MaxEntries: 2}, expected: 3},
+		{name: "size counts the newline", policy: RetentionPolicy{MaxSize: 32}, expected: 3},
+		{name: "age dates unparsed records like the one before", policy: RetentionPolicy{MaxAge: 25 * 24 * time.Hour}, expected: 2},
+		{name: "age", policy: RetentionPolicy{MaxAge: 5 * 24 * time.Hour}, expected: 4},
+		{name: "strictest limit wins", policy: RetentionPolicy{MaxEntries: 4, MaxAge: 15 * 24 * time.Hour}, expected: 3},
+		{name: "everything", policy: RetentionPolicy{MaxSize: 1}, expected: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordsToDrop(records, tt.policy, now); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLogService_Prune(t *testing.T) {
+	line := func(day int, message string) string {
+		stamp := time.Date(2024, 1, day, 12, 0, 0, 0, time.Local).Format(timestampLayout)
+		return "[" + stamp + "] INFO: " + message + "\n"
+	}
+
+	tests := []struct {
+		name      string
+		config    Config
+		files     map[string]string
+		options   PruneOptions
+		expected  map[string]string // file contents afterwards; "" for removed files
+		errorMsg  string
+		printed   string
+		unchanged bool
+	}{
+		{
+			name:     "keeps the newest entries of an append file",
+			config:   Config{LogFile: "/logs/app.log", WriteMode: "append", RetainEntries: 2},
+			files:    map[string]string{"/logs/app.log": line(1, "one") + line(2, "two") + line(3, "three")},
+			expected: map[string]string{"/logs/app.log": line(2, "two") + line(3, "three")},
+			printed:  "Removed 1 entries",
+		},
+		{
+			name:     "keeps the top of an old prepend file",
+			config:   Config{LogFile: "/logs/app.log", WriteMode: "prepend", RetainEntries: 2},
+			files:    map[string]string{"/logs/app.log": line(3, "three") + line(2, "two") + line(1, "one")},
+			expected: map[string]string{"/logs/app.log": line(3, "three") + line(2, "two")},
+			printed:  "Removed 1 entries",
+		},
+		{
+			name:     "options override the configured policy",
+			config:   Config{LogFile: "/logs/app.log", FileOrder: FileOrderOldestFirst, RetainEntries: 2},
+			files:    map[string]string{"/logs/app.log": line(1, "one") + line(2, "two") + line(3, "three")},
+			options:  PruneOptions{RetainEntries: 1},
+			expected: map[string]string{"/logs/app.log": line(3, "three")},
+		},
+		{
+			name:   "removes whole rotated files and trims the next",
+			config: Config{LogFile: "/logs/app.log", RetainEntries: 3},
+			files: map[string]string{
+				"/logs/app.log.2": line(1, "one") + line(2, "two"),
+				"/logs/app.log.1": line(3, "three") + line(4, "four"),
+				"/logs/app.log":   line(5, "five") + line(6, "six"),
+			},
+			expected: map[string]string{
+				"/logs/app.log.2": "",
+				"/logs/app.log.1": line(4, "four"),
+				"/logs/app.log":   line(5, "five") + line(6, "six"),
+			},
+			printed: "removed 1 of 2 entries from /logs/app.log.1",
+		},
+		{
+			name:      "dry run changes nothing",
+			config:    Config{LogFile: "/logs/app.log", RetainEntries: 1},
+			files:     map[string]string{"/logs/app.log": line(1, "one") + line(2, "two")},
+			options:   PruneOptions{DryRun: true},
+			printed:   "Dry run: would remove 1 entries",
+			unchanged: true,
+		},
+		{
+			name:      "nothing to prune",
+			config:    Config{LogFile: "/logs/app.log", RetainEntries: 5},
+			files:     map[string]string{"/logs/app.log": line(1, "one")},
+			printed:   "Nothing to prune",
+			unchanged: true,
+		},
+		{
+			name:     "emptied log file is kept",
+			config:   Config{LogFile: "/logs/app.log", RetainAge: "1h"},
+			files:    map[string]string{"/logs/app.log": line(1, "one")},
+			expected: map[string]string{"/logs/app.log": ""},
+		},
+		{
+			name: "prunes every file sink",
+			config: Config{
+				Sinks:         []SinkConfig{{Path: "/logs/app.log"}, {Path: "/logs/errors.log", MinLevel: "error"}},
+				RetainEntries: 1,
+			},
+			files: map[string]string{
+				"/logs/app.log":    line(1, "one") + line(2, "two"),
+				"/logs/errors.log": line(1, "one") + line(2, "two"),
+			},
+			expected: map[string]string{
+				"/logs/app.log":    line(2, "two"),
+				"/logs/errors.log": line(2, "two"),
+			},
+			printed: "Removed 1 entries from /logs/errors.log",
+		},
+		{
+			name:     "no policy",
+			config:   Config{LogFile: "/logs/app.log"},
+			files:    map[string]string{"/logs/app.log": line(1, "one")},
+			errorMsg: "no retention policy set",
+		},
+		{
+			name:     "invalid override",
+			config:   Config{LogFile: "/logs/app.log"},
+			options:  PruneOptions{RetainAge: "forever"},
+			errorMsg: "invalid age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(tt.config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			for path, content := range tt.files {
+				mockFS.readFiles[path] = []byte(content)
+			}
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			err := logService.Prune(tt.options)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.printed != "" && !mockPrinter.ContainsMessage(tt.printed) {
+				t.Errorf("Expected output containing %q, got %v", tt.printed, mockPrinter.GetMessages())
+			}
+
+			if tt.unchanged {
+				for path, content := range tt.files {
+					if got := string(mockFS.readFiles[path]); got != content {
+						t.Errorf("Expected %s to be unchanged, got %q", path, got)
+					}
+				}
+				return
+			}
+			for path, content := range tt.expected {
+				got, exists := mockFS.readFiles[path]
+				if content == "" && path != tt.config.LogFile {
+					if exists {
+						t.Errorf("Expected %s to be removed", path)
+					}
+					continue
+				}
+				if string(got) != content {
+					t.Errorf("Expected %s to contain %q, got %q", path, content, string(got))
+				}
+			}
+		})
+	}
+}
+
+func TestLogService_Prune_Compressed(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configJSON, _ := json.Marshal(Config{LogFile: "/logs/app.log", RetainEntries: 2})
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockFS.readFiles["/logs/app.log.1.gz"] = gzipData(t, "[2024-01-01 12:00:00] INFO: one\n[2024-01-02 12:00:00] INFO: two\n")
+	mockFS.readFiles["/logs/app.log"] = []byte("[2024-01-03 12:00:00] INFO: three\n")
+
+	logService := NewLogService(NewConfigService(mockFS, &MockPrinter{}), mockFS, &MockPrinter{})
+	if err := logService.Prune(PruneOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, err := readSegment(mockFS, "/logs/app.log.1.gz")
+	if err != nil {
+		t.Fatalf("Expected compressed file to stay readable, got %v", err)
+	}
+	if string(data) != "[2024-01-02 12:00:00] INFO: two\n" {
+		t.Errorf("Expected oldest entry to be removed, got %q", string(data))
+	}
+}
+
+func TestConfigService_Retention(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      ConfigUpdate
+		expectError bool
+	}{
+		{name: "age", update: ConfigUpdate{LogFile: "./test.log", RetainAge: "30d"}},
+		{name: "size", update: ConfigUpdate{LogFile: "./test.log", RetainSize: "1GB"}},
+		{name: "entries", update: ConfigUpdate{LogFile: "./test.log", RetainEntries: 1000}},
+		{name: "invalid age", update: ConfigUpdate{LogFile: "./test.log", RetainAge: "a month"}, expectError: true},
+		{name: "invalid size", update: ConfigUpdate{LogFile: "./test.log", RetainSize: "big"}, expectError: true},
+		{name: "negative entries", update: ConfigUpdate{LogFile: "./test.log", RetainEntries: -5}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+
+			configService := NewConfigService(mockFS, &MockPrinter{})
+			err := configService.UpdateConfig(tt.update)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
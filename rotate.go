@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBackups is how many rotated copies of the log file are kept
+// when size rotation is on and the config does not set a limit.
+const DefaultMaxBackups = 5
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+}
+
+// parseSize parses a size such as "512", "64K", "10MB" or "1G" into bytes.
+// Units are powers of 1024. An empty value means no limit and returns 0.
+func parseSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	trimmed := strings.TrimSpace(value)
+	digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(trimmed[digits:]))]
+	if digits == 0 || !ok {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes with an optional K, M or G suffix", value)
+	}
+
+	n, err := strconv.ParseInt(trimmed[:digits], 10, 64)
+	if err != nil || n > (1<<62)/unit {
+		return 0, fmt.Errorf("invalid size %q: too large", value)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid size %q: must be greater than zero", value)
+	}
+	return n * unit, nil
+}
+
+// maxBackups returns the number of rotated copies to keep.
+func (c *Config) maxBackups() int {
+	if c.MaxBackups <= 0 {
+		return DefaultMaxBackups
+	}
+	return c.MaxBackups
+}
+
+// backupPath returns the path of the nth rotated copy of a log file;
+// app.log.1 is the most recent.
+func backupPath(logFile string, n int) string {
+	return logFile + "." + strconv.Itoa(n)
+}
+
+// backupFile is a rotated copy of a log file, possibly compressed.
+type backupFile struct {
+	n          int
+	compressed bool
+}
+
+// path returns where the copy is stored.
+func (b backupFile) path(logFile string) string {
+	if b.compressed {
+		return backupPath(logFile, b.n) + compressedExt
+	}
+	return backupPath(logFile, b.n)
+}
+
+// numberedBackups returns the existing rotated copies of a log file,
+// oldest last. A copy left both plain and compressed by an interrupted
+// compression is listed twice, plain first.
+func numberedBackups(fs FileSystem, logFile string) ([]backupFile, error) {
+	entries, err := fs.ReadDir(filepath.Dir(logFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing rotated log files: %w", err)
+	}
+
+	prefix := filepath.Base(logFile) + "."
+	var backups []backupFile
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		number, compressed := strings.CutSuffix(suffix, compressedExt)
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 || strconv.Itoa(n) != number {
+			continue
+		}
+		backups = append(backups, backupFile{n: n, compressed: compressed})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].n != backups[j].n {
+			return backups[i].n < backups[j].n
+		}
+		return !backups[i].compressed && backups[j].compressed
+	})
+	return backups, nil
+}
+
+// rotateIfNeeded rotates logFile when appending size more bytes would take
+// it past the configured maximum size. The caller must hold the log file
+// lock.
+func rotateIfNeeded(fs FileSystem, config *Config, logFile string, size int) error {
+	maxSize, err := parseSize(config.MaxSize)
+	if err != nil || maxSize == 0 {
+		return err
+	}
+
+	info, err := fs.Stat(logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error checking log file size: %w", err)
+	}
+
+	// An empty file is never rotated, so an entry larger than the limit
+	// still gets written.
+	if info.Size() == 0 || info.Size()+int64(size) <= maxSize {
+		return nil
+	}
+	return rotateLogFile(fs, logFile, config.maxBackups())
+}
+
+// rotateLogFile renames app.log to app.log.1 after shifting each older
+// copy up by one, removing copies that would go past maxBackups.
+func rotateLogFile(fs FileSystem, logFile string, maxBackups int) error {
+	backups, err := numberedBackups(fs, logFile)
+	if err != nil {
+		return err
+	}
+
+	// Work down from the oldest copy so every rename targets a free name
+	for i := len(backups) - 1; i >= 0; i-- {
+		backup := backups[i]
+		if backup.n >= maxBackups {
+			if err := fs.Remove(backup.path(logFile)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error removing old log file: %w", err)
+			}
+			continue
+		}
+		shifted := backupFile{n: backup.n + 1, compressed: backup.compressed}
+		if err := fs.Rename(backup.path(logFile), shifted.path(logFile)); err != nil {
+			return fmt.Errorf("error rotating log file: %w", err)
+		}
+	}
+
+	if err := fs.Rename(logFile, backupPath(logFile, 1)); err != nil {
+		return fmt.Errorf("error rotating log file: %w", err)
+	}
+	return nil
+}
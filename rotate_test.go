@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    int64
+		expectError bool
+	}{
+		{input: "", expected: 0},
+		{input: "512", expected: 512},
+		{input: "512B", expected: 512},
+		{input: "64K", expected: 64 << 10},
+		{input: "64kb", expected: 64 << 10},
+		{input: "10MB", expected: 10 << 20},
+		{input: "10 MB", expected: 10 << 20},
+		{input: "1G", expected: 1 << 30},
+		{input: "0", expectError: true},
+		{input: "MB", expectError: true},
+		{input: "1.5MB", expectError: true},
+		{input: "-1MB", expectError: true},
+		{input: "10TB", expectError: true},
+		{input: "99999999999G", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSize(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRotateLogFile(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.readFiles["/logs/app.log"] = []byte("current\n")
+	mockFS.readFiles["/logs/app.log.1"] = []byte("backup 1\n")
+	mockFS.readFiles["/logs/app.log.2"] = []byte("backup 2\n")
+	mockFS.readFiles["/logs/app.log.3"] = []byte("backup 3\n")
+	mockFS.readFiles["/logs/app.log.7"] = []byte("left from a larger limit\n")
+	// Files that are not numbered backups are left alone
+	mockFS.readFiles["/logs/app.log.lock"] = []byte{}
+	mockFS.readFiles["/logs/app.log.01"] = []byte("not a backup\n")
+	mockFS.readFiles["/logs/other.log.1"] = []byte("other log\n")
+
+	if err := rotateLogFile(mockFS, "/logs/app.log", 3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"/logs/app.log.1":    "current\n",
+		"/logs/app.log.2":    "backup 1\n",
+		"/logs/app.log.3":    "backup 2\n",
+		"/logs/app.log.lock": "",
+		"/logs/app.log.01":   "not a backup\n",
+		"/logs/other.log.1":  "other log\n",
+	}
+	for path, content := range expected {
+		data, exists := mockFS.readFiles[path]
+		if !exists {
+			t.Errorf("Expected %s to exist", path)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Expected %s to contain %q, got %q", path, content, string(data))
+		}
+	}
+	for _, path := range []string{"/logs/app.log", "/logs/app.log.4", "/logs/app.log.7"} {
+		if _, exists := mockFS.readFiles[path]; exists {
+			t.Errorf("Expected %s not to exist", path)
+		}
+	}
+}
+
+func TestRotateLogFile_Errors(t *testing.T) {
+	mockFS := NewMockFileSystem()
+	mockFS.readFiles["/logs/app.log"] = []byte("current\n")
+	mockFS.renameErr = os.ErrPermission
+
+	err := rotateLogFile(mockFS, "/logs/app.log", 3)
+	if err == nil || !strings.Contains(err.Error(), "error rotating log file") {
+		t.Errorf("Expected rotation error, got %v", err)
+	}
+	if _, exists := mockFS.readFiles["/logs/app.log"]; !exists {
+		t.Error("Expected log file to stay in place")
+	}
+}
+
+func TestLogService_Rotation(t *testing.T) {
+	fs := NewTempHomeFileSystem(t)
+	logFile := filepath.Join(fs.home, "app.log")
+	printer := &MockPrinter{}
+	configService := NewConfigService(fs, printer)
+	logService := NewLogService(configService, fs, printer)
+
+	// Each entry is 53 bytes, so two fit in a file
+	const maxSize = 110
+	err := configService.UpdateConfig(ConfigUpdate{
+		LogFile:    logFile,
+		Template:   "{{.Time}} {{.Message}}",
+		TimeFormat: "unixnano",
+		MaxSize:    fmt.Sprint(maxSize),
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 1; i <= 9; i++ {
+		if err := logService.AppendLog("info", fmt.Sprintf("entry %02d padded to a fixed width", i)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	expected := map[string][]string{
+		logFile + ".2": {"entry 05", "entry 06"},
+		logFile + ".1": {"entry 07", "entry 08"},
+		logFile:        {"entry 09"},
+	}
+	for path, messages := range expected {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected %s to exist, got %v", path, err)
+		}
+		if len(data) > maxSize {
+			t.Errorf("Expected %s to be at most %d bytes, got %d", path, maxSize, len(data))
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != len(messages) {
+			t.Fatalf("Expected %d entries in %s, got %q", len(messages), path, lines)
+		}
+		for k, message := range messages {
+			if !strings.Contains(lines[k], message) {
+				t.Errorf("Expected entry %d of %s to contain %q, got %q", k, path, message, lines[k])
+			}
+		}
+	}
+	if _, err := os.Stat(logFile + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected no third backup, got %v", err)
+	}
+}
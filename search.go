@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// highlight marks text found by 'slog view --grep'.
+const highlight = Bold + Red
+
+// Search finds a pattern in entries for 'slog view --grep'. Each entry is
+// searched as a whole, as it is shown, so a match can span the lines of a
+// multi-line message and a matching entry is kept with all of its lines.
+type Search struct {
+	pattern *regexp.Regexp
+	invert  bool
+}
+
+// newSearch compiles a --grep pattern: plain text unless regex is set.
+func newSearch(pattern string, regex, ignoreCase, invert bool) (*Search, error) {
+	expr := pattern
+	if !regex {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --grep pattern %q: %w", pattern, err)
+	}
+	return &Search{pattern: compiled, invert: invert}, nil
+}
+
+// search returns the --grep search of a view, or nil when there is none.
+func (o ViewOptions) search() (*Search, error) {
+	if o.Grep == "" {
+		if o.Regex || o.IgnoreCase || o.Invert {
+			return nil, fmt.Errorf("--regex, --ignore-case and --invert need a --grep pattern")
+		}
+		return nil, nil
+	}
+	return newSearch(o.Grep, o.Regex, o.IgnoreCase, o.Invert)
+}
+
+// Matches reports whether an entry shown as text is kept: whether it
+// contains the pattern, or does not when the search is inverted.
+func (s *Search) Matches(text string) bool {
+	return s.pattern.MatchString(strings.TrimSuffix(text, "\n")) != s.invert
+}
+
+// Highlight colors each match of the pattern in text. Nothing is colored
+// in an inverted search, whose entries do not contain the pattern.
+func (s *Search) Highlight(text string) string {
+	if s.invert {
+		return text
+	}
+	body, newline := strings.CutSuffix(text, "\n")
+	body = s.pattern.ReplaceAllStringFunc(body, func(match string) string {
+		if match == "" {
+			return match
+		}
+		// Color each line of a match on its own, so no line is left
+		// colored past its end
+		lines := strings.Split(match, "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = highlight + line + Reset
+			}
+		}
+		return strings.Join(lines, "\n")
+	})
+	if newline {
+		body += "\n"
+	}
+	return body
+}
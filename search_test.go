@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	const entry = "[2024-01-15 10:30:00] ERROR: Request failed\n  upstream timeout after 30s\n"
+
+	tests := []struct {
+		name          string
+		pattern       string
+		regex         bool
+		ignoreCase    bool
+		invert        bool
+		text          string
+		expectedMatch bool
+		expectedText  string
+	}{
+		{
+			name:          "plain text",
+			pattern:       "failed",
+			text:          entry,
+			expectedMatch: true,
+			expectedText:  "[2024-01-15 10:30:00] ERROR: Request " + highlight + "failed" + Reset + "\n  upstream timeout after 30s\n",
+		},
+		{
+			name:          "continuation line",
+			pattern:       "timeout",
+			text:          entry,
+			expectedMatch: true,
+			expectedText:  "[2024-01-15 10:30:00] ERROR: Request failed\n  upstream " + highlight + "timeout" + Reset + " after 30s\n",
+		},
+		{
+			name:          "plain text is not a regex",
+			pattern:       "30.",
+			text:          entry,
+			expectedMatch: false,
+		},
+		{
+			name:          "case differs",
+			pattern:       "error",
+			text:          entry,
+			expectedMatch: false,
+		},
+		{
+			name:          "ignore case",
+			pattern:       "error",
+			ignoreCase:    true,
+			text:          entry,
+			expectedMatch: true,
+			expectedText:  "[2024-01-15 10:30:00] " + highlight + "ERROR" + Reset + ": Request failed\n  upstream timeout after 30s\n",
+		},
+		{
+			name:          "regex",
+			pattern:       `\d+s`,
+			regex:         true,
+			text:          entry,
+			expectedMatch: true,
+			expectedText:  "[2024-01-15 10:30:00] ERROR: Request failed\n  upstream timeout after " + highlight + "30s" + Reset + "\n",
+		},
+		{
+			name:          "regex across lines",
+			pattern:       `failed\n\s+upstream`,
+			regex:         true,
+			text:          entry,
+			expectedMatch: true,
+			expectedText:  "[2024-01-15 10:30:00] ERROR: Request " + highlight + "failed" + Reset + "\n" + highlight + "  upstream" + Reset + " timeout after 30s\n",
+		},
+		{
+			name:          "invert",
+			pattern:       "timeout",
+			invert:        true,
+			text:          entry,
+			expectedMatch: false,
+		},
+		{
+			name:          "invert keeps entries without the pattern",
+			pattern:       "healthcheck",
+			invert:        true,
+			text:          entry,
+			expectedMatch: true,
+			expectedText:  entry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search, err := newSearch(tt.pattern, tt.regex, tt.ignoreCase, tt.invert)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := search.Matches(tt.text); got != tt.expectedMatch {
+				t.Fatalf("Expected match %v, got %v", tt.expectedMatch, got)
+			}
+			if !tt.expectedMatch {
+				return
+			}
+			if got := search.Highlight(tt.text); got != tt.expectedText {
+				t.Errorf("Expected %q, got %q", tt.expectedText, got)
+			}
+		})
+	}
+}
+
+func TestLogService_ViewLog_Grep(t *testing.T) {
+	const log = "[2024-01-15 10:30:00] INFO: GET /health 200\n" +
+		"[2024-01-15 10:31:00] ERROR: Request failed\n" +
+		"  upstream timeout after 30s\n" +
+		"[2024-01-15 10:32:00] WARN service=api: Slow request\n" +
+		"[2024-01-15 10:33:00] INFO: GET /health 200\n"
+
+	tests := []struct {
+		name           string
+		options        ViewOptions
+		expectedOutput string
+		errorMsg       string
+	}{
+		{
+			name:           "multi-line entry is kept together",
+			options:        ViewOptions{Grep: "timeout"},
+			expectedOutput: "[2024-01-15 10:31:00] ERROR: Request failed\n  upstream " + highlight + "timeout" + Reset + " after 30s\n",
+		},
+		{
+			name:           "ignore case",
+			options:        ViewOptions{Grep: "request", IgnoreCase: true},
+			expectedOutput: "[2024-01-15 10:31:00] ERROR: " + highlight + "Request" + Reset + " failed\n  upstream timeout after 30s\n[2024-01-15 10:32:00] WARN service=api: Slow " + highlight + "request" + Reset + "\n",
+		},
+		{
+			name:           "fields are searched",
+			options:        ViewOptions{Grep: "service=api"},
+			expectedOutput: "[2024-01-15 10:32:00] WARN " + highlight + "service=api" + Reset + ": Slow request\n",
+		},
+		{
+			name:           "regex",
+			options:        ViewOptions{Grep: `(ERROR|WARN)\b`, Regex: true},
+			expectedOutput: "[2024-01-15 10:31:00] " + highlight + "ERROR" + Reset + ": Request failed\n  upstream timeout after 30s\n[2024-01-15 10:32:00] " + highlight + "WARN" + Reset + " service=api: Slow request\n",
+		},
+		{
+			name:           "invert",
+			options:        ViewOptions{Grep: "/health", Invert: true},
+			expectedOutput: "[2024-01-15 10:31:00] ERROR: Request failed\n  upstream timeout after 30s\n[2024-01-15 10:32:00] WARN service=api: Slow request\n",
+		},
+		{
+			name:           "with a level",
+			options:        ViewOptions{Grep: "request", IgnoreCase: true, Levels: []string{"warn"}},
+			expectedOutput: "[2024-01-15 10:32:00] WARN service=api: Slow " + highlight + "request" + Reset + "\n",
+		},
+		{
+			name:     "invalid regex",
+			options:  ViewOptions{Grep: "(unclosed", Regex: true},
+			errorMsg: "invalid --grep pattern",
+		},
+		{
+			name:     "ignore case without a pattern",
+			options:  ViewOptions{IgnoreCase: true},
+			errorMsg: "need a --grep pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			config := Config{LogFile: "/tmp/test.log", FileOrder: FileOrderOldestFirst}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readFiles["/tmp/test.log"] = []byte(log)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+			err := logService.ViewLog(tt.options)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Contains(mockPrinter.GetMessages(), tt.expectedOutput) {
+				t.Errorf("Expected output %q, got %v", tt.expectedOutput, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
@@ -0,0 +1,649 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sink types. A sink that does not name a type is a file.
+const (
+	SinkFile     = "file"
+	SinkSyslog   = "syslog"
+	SinkJournald = "journald"
+	SinkWebhook  = "webhook"
+	SinkOTLP     = "otlp"
+	SinkLoki     = "loki"
+)
+
+// SinkConfig is one destination entries are written to. Empty format and
+// write mode fall back to the config's defaults, and an empty minimum level
+// accepts every entry. Path, Format and WriteMode apply to file sinks;
+// Address, Facility and AppName to syslog and journald sinks; URL, Headers,
+// BatchSize, Timeout and Retries to webhook, OTLP and Loki sinks; Encoding
+// and Service to OTLP sinks; Labels to Loki sinks. Retries is a pointer so
+// that 0, which turns retrying off, differs from the unset default.
+type SinkConfig struct {
+	Type      string            `json:"type,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Format    string            `json:"format,omitempty"`
+	WriteMode string            `json:"write_mode,omitempty"`
+	MinLevel  string            `json:"min_level,omitempty"`
+	Address   string            `json:"address,omitempty"`
+	Facility  string            `json:"facility,omitempty"`
+	AppName   string            `json:"app_name,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	BatchSize int               `json:"batch_size,omitempty"`
+	Timeout   string            `json:"timeout,omitempty"`
+	Retries   *int              `json:"retries,omitempty"`
+	Encoding  string            `json:"encoding,omitempty"`
+	Service   string            `json:"service,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// kind returns the sink type, defaulting to a file.
+func (s SinkConfig) kind() string {
+	if s.Type == "" {
+		return SinkFile
+	}
+	return s.Type
+}
+
+// target returns what the sink writes to; it identifies the sink when
+// removing it.
+func (s SinkConfig) target() string {
+	switch s.kind() {
+	case SinkSyslog:
+		if s.Address == "" {
+			return defaultSyslogAddress
+		}
+		return s.Address
+	case SinkJournald:
+		if s.Address == "" {
+			return defaultJournalSocket
+		}
+		return s.Address
+	case SinkWebhook, SinkOTLP, SinkLoki:
+		return s.URL
+	}
+	return s.Path
+}
+
+// String describes the sink for 'slog config'.
+func (s SinkConfig) String() string {
+	var details []string
+	if s.kind() != SinkFile {
+		details = append(details, s.kind())
+	}
+	if s.Format != "" {
+		details = append(details, s.Format)
+	}
+	if s.WriteMode != "" {
+		details = append(details, s.WriteMode)
+	}
+	if s.Encoding != "" {
+		details = append(details, s.Encoding)
+	}
+	if s.Service != "" {
+		details = append(details, "service "+s.Service)
+	}
+	if len(s.Labels) > 0 {
+		details = append(details, "labels "+displayLabels(s.Labels))
+	}
+	if s.Facility != "" {
+		details = append(details, "facility "+s.Facility)
+	}
+	if s.AppName != "" {
+		details = append(details, "as "+s.AppName)
+	}
+	if s.BatchSize != 0 {
+		details = append(details, fmt.Sprintf("batches of %d", s.BatchSize))
+	}
+	if s.MinLevel != "" {
+		details = append(details, s.MinLevel+" and above")
+	}
+	if len(details) == 0 {
+		return s.target()
+	}
+	return fmt.Sprintf("%s (%s)", s.target(), strings.Join(details, ", "))
+}
+
+// validateSink checks a sink's settings.
+func (c *Config) validateSink(s SinkConfig) error {
+	switch s.kind() {
+	case SinkFile:
+		if s.Path == "" {
+			return fmt.Errorf("file sink requires a path")
+		}
+		if s.Format != "" {
+			if err := ValidateFormat(s.Format, c.FormatOptions()); err != nil {
+				return err
+			}
+		}
+		if s.WriteMode != "" && s.WriteMode != "append" && s.WriteMode != "prepend" {
+			return fmt.Errorf("write mode must be 'append' or 'prepend'")
+		}
+	case SinkSyslog:
+		if err := validateSyslogSink(s); err != nil {
+			return err
+		}
+	case SinkJournald:
+		if err := validateJournaldSink(s); err != nil {
+			return err
+		}
+	case SinkWebhook:
+		if err := validateHTTPSink(s); err != nil {
+			return err
+		}
+	case SinkOTLP:
+		if err := validateOTLPSink(s); err != nil {
+			return err
+		}
+	case SinkLoki:
+		if err := validateLokiSink(s); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q: use '%s', '%s', '%s', '%s', '%s' or '%s'", s.Type, SinkFile, SinkSyslog, SinkJournald, SinkWebhook, SinkOTLP, SinkLoki)
+	}
+
+	if s.MinLevel != "" {
+		if len(c.LevelOrder) > 0 {
+			if _, ok := c.levelRank(s.MinLevel); !ok {
+				return fmt.Errorf("unknown minimum level %q: use one of %s", s.MinLevel, strings.Join(c.LevelOrder, ", "))
+			}
+		} else if _, ok := c.severity(s.MinLevel); !ok {
+			return fmt.Errorf("unknown minimum level %q: use one of %s, or a level with a configured severity", s.MinLevel, strings.Join(severityNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// accepts reports whether an entry at level is at or above the sink's
+// minimum level, by the configured level order or, for configs saved
+// without one, by severity. Levels that are not ranked are always
+// accepted.
+func (c *Config) accepts(s SinkConfig, level string) bool {
+	if s.MinLevel == "" {
+		return true
+	}
+	if len(c.LevelOrder) > 0 {
+		rank, ok := c.levelRank(level)
+		if !ok {
+			return true
+		}
+		minimum, _ := c.levelRank(s.MinLevel)
+		return rank >= minimum
+	}
+	severity, ok := c.severity(level)
+	if !ok {
+		return true
+	}
+	minimum, _ := c.severity(s.MinLevel)
+	return severity <= minimum
+}
+
+// levelSeverities maps level names to syslog severities, most severe
+// first, so 0 is an emergency and 7 is debug output.
+var levelSeverities = map[string]int{
+	"emerg":     0,
+	"emergency": 0,
+	"alert":     1,
+	"crit":      2,
+	"critical":  2,
+	"fatal":     2,
+	"err":       3,
+	"error":     3,
+	"warn":      4,
+	"warning":   4,
+	"notice":    5,
+	"info":      6,
+	"debug":     7,
+	"trace":     7,
+}
+
+// severityNames lists the known level names, most severe first.
+func severityNames() []string {
+	names := make([]string, 0, len(levelSeverities))
+	for name := range levelSeverities {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if levelSeverities[names[i]] != levelSeverities[names[j]] {
+			return levelSeverities[names[i]] < levelSeverities[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// parseSinkSpec parses a sink given on the command line as comma
+// separated key=value settings, e.g. 'path=./errors.log,min_level=error'.
+// A leading value without a key is the path, 'header.<Name>=value' sets a
+// request header and 'label.<name>=value' a Loki label.
+func parseSinkSpec(spec string) (SinkConfig, error) {
+	var sink SinkConfig
+	for i, setting := range strings.Split(spec, ",") {
+		setting = strings.TrimSpace(setting)
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok && i == 0 {
+			key, value = "path", setting
+		} else if !ok {
+			return SinkConfig{}, fmt.Errorf("invalid sink setting %q: expected key=value", setting)
+		}
+
+		key = strings.TrimSpace(key)
+		if name, ok := strings.CutPrefix(key, "header."); ok {
+			if sink.Headers == nil {
+				sink.Headers = make(map[string]string)
+			}
+			sink.Headers[name] = strings.TrimSpace(value)
+			continue
+		}
+		if name, ok := strings.CutPrefix(key, "label."); ok {
+			if sink.Labels == nil {
+				sink.Labels = make(map[string]string)
+			}
+			sink.Labels[name] = strings.TrimSpace(value)
+			continue
+		}
+
+		switch key {
+		case "type":
+			sink.Type = strings.TrimSpace(value)
+		case "path":
+			sink.Path = strings.TrimSpace(value)
+		case "format":
+			sink.Format = strings.TrimSpace(value)
+		case "mode", "write_mode":
+			sink.WriteMode = strings.TrimSpace(value)
+		case "min_level", "level":
+			sink.MinLevel = strings.TrimSpace(value)
+		case "address":
+			sink.Address = strings.TrimSpace(value)
+		case "facility":
+			sink.Facility = strings.TrimSpace(value)
+		case "app_name", "app":
+			sink.AppName = strings.TrimSpace(value)
+		case "url":
+			sink.URL = strings.TrimSpace(value)
+		case "batch_size", "batch":
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return SinkConfig{}, fmt.Errorf("invalid batch size %q: %w", value, err)
+			}
+			sink.BatchSize = n
+		case "timeout":
+			sink.Timeout = strings.TrimSpace(value)
+		case "retries":
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return SinkConfig{}, fmt.Errorf("invalid retries %q: %w", value, err)
+			}
+			sink.Retries = &n
+		case "encoding":
+			sink.Encoding = strings.TrimSpace(value)
+		case "service":
+			sink.Service = strings.TrimSpace(value)
+		default:
+			return SinkConfig{}, fmt.Errorf("unknown sink setting %q", key)
+		}
+	}
+	return sink, nil
+}
+
+// sinkFlags collects repeated sink specs.
+type sinkFlags []SinkConfig
+
+func (f *sinkFlags) String() string {
+	var specs []string
+	for _, sink := range *f {
+		specs = append(specs, sink.String())
+	}
+	return strings.Join(specs, "; ")
+}
+
+func (f *sinkFlags) Set(spec string) error {
+	sink, err := parseSinkSpec(spec)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, sink)
+	return nil
+}
+
+// listFlags collects repeated string flags.
+type listFlags []string
+
+func (f *listFlags) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *listFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// migrateLogFile moves the single log file of a config written before
+// sinks existed into the sink list.
+func (c *Config) migrateLogFile() {
+	if c.LogFile != "" && len(c.Sinks) == 0 {
+		c.Sinks = []SinkConfig{{Path: c.LogFile}}
+	}
+	c.LogFile = ""
+}
+
+// setMainLogFile points the first file sink at path, adding a file sink
+// when there is none.
+func (c *Config) setMainLogFile(path string) {
+	for i, sink := range c.Sinks {
+		if sink.kind() == SinkFile {
+			c.Sinks[i].Path = path
+			return
+		}
+	}
+	c.Sinks = append([]SinkConfig{{Path: path}}, c.Sinks...)
+}
+
+// removeSink removes the sink writing to target.
+func (c *Config) removeSink(target string) error {
+	for i, sink := range c.Sinks {
+		if sink.target() == target {
+			c.Sinks = append(c.Sinks[:i:i], c.Sinks[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no sink writes to %q", target)
+}
+
+// addSink adds a sink, replacing any sink with the same target.
+func (c *Config) addSink(sink SinkConfig) {
+	for i, existing := range c.Sinks {
+		if existing.target() == sink.target() {
+			c.Sinks[i] = sink
+			return
+		}
+	}
+	c.Sinks = append(c.Sinks, sink)
+}
+
+// fileConfig returns the config a file sink is written with: the shared
+// settings, with LogFile, Format and WriteMode taken from the sink.
+func (c *Config) fileConfig(sink SinkConfig) *Config {
+	file := *c
+	file.Sinks = nil
+	file.LogFile = sink.Path
+	if sink.Format != "" {
+		file.Format = sink.Format
+	}
+	if sink.WriteMode != "" {
+		file.WriteMode = sink.WriteMode
+	}
+	return &file
+}
+
+// logFiles returns the configs of every file sink, in config order,
+// followed by the files levels are routed to.
+func (c *Config) logFiles() []*Config {
+	var files []*Config
+	seen := make(map[string]bool)
+	for _, sink := range c.Sinks {
+		if sink.kind() == SinkFile && !seen[sink.Path] {
+			seen[sink.Path] = true
+			files = append(files, c.fileConfig(sink))
+		}
+	}
+	for _, file := range c.routedFiles() {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, c.fileConfig(c.routedSink(file)))
+		}
+	}
+	return files
+}
+
+// routedFiles returns the files levels are routed to, sorted by the name
+// of the first level routed to each.
+func (c *Config) routedFiles() []string {
+	names := make([]string, 0, len(c.LogLevels))
+	for name := range c.LogLevels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []string
+	for _, name := range names {
+		if file := c.LogLevels[name].File; file != "" && !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// mainSinkIndex returns the index of the first file sink, or -1.
+func (c *Config) mainSinkIndex() int {
+	for i, sink := range c.Sinks {
+		if sink.kind() == SinkFile {
+			return i
+		}
+	}
+	return -1
+}
+
+// routedSink returns the sink for a level's own file, which is written in
+// the main log file's format and write mode.
+func (c *Config) routedSink(path string) SinkConfig {
+	var sink SinkConfig
+	if i := c.mainSinkIndex(); i >= 0 {
+		sink = c.Sinks[i]
+	}
+	sink.Path = path
+	sink.MinLevel = ""
+	return sink
+}
+
+// sinksFor returns the sinks an entry at level is written to. A level
+// with its own file goes there in place of the main log file, whatever the
+// main file's minimum level.
+func (c *Config) sinksFor(level string) []SinkConfig {
+	route := c.levelFile(level)
+	main := c.mainSinkIndex()
+
+	var sinks []SinkConfig
+	if main < 0 && route != "" {
+		sinks = append(sinks, c.routedSink(route))
+	}
+	for i, sink := range c.Sinks {
+		if i == main && route != "" {
+			sinks = append(sinks, c.routedSink(route))
+			continue
+		}
+		if c.accepts(sink, level) {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// mainLog returns the config of the first file sink, which 'slog view'
+// shows.
+func (c *Config) mainLog() (*Config, error) {
+	files := c.logFiles()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file sink configured; add one with 'slog config --add-sink <path>'")
+	}
+	return files[0], nil
+}
+
+// viewLogs returns the configs of the files 'slog view' shows: the main
+// log file, then the files levels are routed to in its place.
+func (c *Config) viewLogs() ([]*Config, error) {
+	main, err := c.mainLog()
+	if err != nil {
+		return nil, err
+	}
+	logs := []*Config{main}
+	for _, file := range c.routedFiles() {
+		if file != main.LogFile {
+			logs = append(logs, c.fileConfig(c.routedSink(file)))
+		}
+	}
+	return logs, nil
+}
+
+// displaySinks lists the sinks for 'slog config'.
+func displaySinks(config *Config) string {
+	if len(config.Sinks) == 0 {
+		return "none"
+	}
+	var sinks []string
+	for _, sink := range config.Sinks {
+		sinks = append(sinks, sink.String())
+	}
+	return strings.Join(sinks, "; ")
+}
+
+// displayMainLog returns the file 'slog view' shows.
+func displayMainLog(config *Config) string {
+	file, err := config.mainLog()
+	if err != nil {
+		return "none"
+	}
+	return file.LogFile
+}
+
+// Sink is a destination entries are written to.
+type Sink interface {
+	// Write stores one entry and returns where it went.
+	Write(entry Entry) (string, error)
+	// Close releases the sink once no more entries will be written.
+	Close() error
+}
+
+// newSink opens the sink described by sink.
+func (ls *LogService) newSink(config *Config, sink SinkConfig) (Sink, error) {
+	switch sink.kind() {
+	case SinkFile:
+		return &fileSink{ls: ls, config: config.fileConfig(sink)}, nil
+	case SinkSyslog:
+		return newSyslogSink(config, sink)
+	case SinkJournald:
+		return newJournaldSink(config, sink)
+	case SinkWebhook:
+		return newWebhookSink(ls.fs, sink)
+	case SinkOTLP:
+		return newOTLPSink(ls.fs, config, sink)
+	case SinkLoki:
+		return newLokiSink(ls.fs, sink)
+	}
+	return nil, fmt.Errorf("unknown sink type %q", sink.Type)
+}
+
+// sink returns the open sink for a sink config, opening it on first use
+// so later entries from the same run reuse it, or the error it failed
+// to open with.
+func (ls *LogService) sink(config *Config, sink SinkConfig) (Sink, error) {
+	key := sink.kind() + ":" + sink.target()
+	if open, ok := ls.sinks[key]; ok {
+		return open, nil
+	}
+	if err, ok := ls.sinkErrs[key]; ok {
+		return nil, err
+	}
+	open, err := ls.newSink(config, sink)
+	if err != nil {
+		// Not retried for later entries, which would each wait for it
+		if ls.sinkErrs == nil {
+			ls.sinkErrs = make(map[string]error)
+		}
+		ls.sinkErrs[key] = err
+		return nil, err
+	}
+	if ls.sinks == nil {
+		ls.sinks = make(map[string]Sink)
+	}
+	ls.sinks[key] = open
+	ls.sinkOrder = append(ls.sinkOrder, key)
+	return open, nil
+}
+
+// Close closes every sink opened by writes and returns the first error.
+func (ls *LogService) Close() error {
+	var firstErr error
+	for _, key := range ls.sinkOrder {
+		if err := ls.sinks[key].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	ls.sinks = nil
+	ls.sinkOrder = nil
+	ls.sinkErrs = nil
+	return firstErr
+}
+
+// fileSink appends entries to a log file, rotating and compressing it as
+// configured.
+type fileSink struct {
+	ls     *LogService
+	config *Config
+}
+
+func (s *fileSink) Write(entry Entry) (string, error) {
+	ls, config := s.ls, s.config
+
+	formatter, err := NewFormatter(config.Format, config.FormatOptions())
+	if err != nil {
+		return "", err
+	}
+	logEntry, err := formatter.Format(entry)
+	if err != nil {
+		return "", err
+	}
+
+	lock, err := lockLogFile(ls.fs, config)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := lock.Close(); err != nil {
+			fmt.Printf("Warning: failed to release log file lock: %v\n", err)
+		}
+	}()
+
+	logFile, err := config.logFileAt(entry.Time)
+	if err != nil {
+		return "", err
+	}
+
+	if err := rotateIfNeeded(ls.fs, config, logFile, len(logEntry)); err != nil {
+		return "", err
+	}
+
+	// Entries are always appended, so a write costs the same however long
+	// the log is; prepend mode shows the file newest first when viewing it.
+	file, err := ls.fs.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("error opening log file: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close log file: %v\n", err)
+		}
+	}()
+
+	_, err = file.Write([]byte(logEntry))
+	if err != nil {
+		return "", fmt.Errorf("error writing to log file: %w", err)
+	}
+
+	ls.startCompression(config, logFile)
+	return logFile, nil
+}
+
+func (s *fileSink) Close() error {
+	return nil
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSinkSpec(t *testing.T) {
+	five, zero := 5, 0
+	tests := []struct {
+		spec        string
+		expected    SinkConfig
+		expectError bool
+	}{
+		{spec: "./errors.log", expected: SinkConfig{Path: "./errors.log"}},
+		{spec: "./errors.log,min_level=error", expected: SinkConfig{Path: "./errors.log", MinLevel: "error"}},
+		{
+			spec:     "path=/var/log/team.log, format=jsonl, mode=prepend",
+			expected: SinkConfig{Path: "/var/log/team.log", Format: "jsonl", WriteMode: "prepend"},
+		},
+		{spec: "type=file,path=./a.log,level=warn", expected: SinkConfig{Type: "file", Path: "./a.log", MinLevel: "warn"}},
+		{
+			spec: "type=webhook,url=https://hooks.example.com/logs,header.Authorization=Bearer abc,batch_size=50,timeout=2s,retries=5",
+			expected: SinkConfig{
+				Type:      "webhook",
+				URL:       "https://hooks.example.com/logs",
+				Headers:   map[string]string{"Authorization": "Bearer abc"},
+				BatchSize: 50,
+				Timeout:   "2s",
+				Retries:   &five,
+			},
+		},
+		{spec: "type=webhook,url=https://hooks.example.com,batch_size=many", expectError: true},
+		{spec: "type=webhook,url=https://hooks.example.com,retries=0", expected: SinkConfig{Type: "webhook", URL: "https://hooks.example.com", Retries: &zero}},
+		{
+			spec:     "type=otlp,url=http://collector:4318,encoding=json,service=backup",
+			expected: SinkConfig{Type: "otlp", URL: "http://collector:4318", Encoding: "json", Service: "backup"},
+		},
+		{
+			spec:     "type=loki,url=http://loki:3100,label.job=backup,label.env=prod",
+			expected: SinkConfig{Type: "loki", URL: "http://loki:3100", Labels: map[string]string{"job": "backup", "env": "prod"}},
+		},
+		{spec: "./a.log,jsonl", expectError: true},
+		{spec: "./a.log,colour=red", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseSinkSpec(tt.spec)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConfig_Accepts(t *testing.T) {
+	levels := map[string]LevelConfig{
+		"success": {Flag: "s", Severity: "notice"},
+		"audit":   {Flag: "a", Severity: "2"},
+	}
+
+	tests := []struct {
+		order    []string
+		minLevel string
+		level    string
+		expected bool
+	}{
+		{minLevel: "", level: "DEBUG", expected: true},
+		{minLevel: "error", level: "ERROR", expected: true},
+		{minLevel: "error", level: "FATAL", expected: true},
+		{minLevel: "error", level: "WARN", expected: false},
+		{minLevel: "warn", level: "WARNING", expected: true},
+		{minLevel: "info", level: "DEBUG", expected: false},
+		{minLevel: "error", level: "TRACE_ID", expected: true},
+		{minLevel: "warn", level: "SUCCESS", expected: false},
+		{minLevel: "info", level: "SUCCESS", expected: true},
+		{minLevel: "error", level: "AUDIT", expected: true},
+		{minLevel: "success", level: "WARN", expected: true},
+		{order: []string{"debug", "info", "audit", "warn", "error"}, minLevel: "audit", level: "WARN", expected: true},
+		{order: []string{"debug", "info", "audit", "warn", "error"}, minLevel: "warn", level: "AUDIT", expected: false},
+		{order: []string{"debug", "info", "audit", "warn", "error"}, minLevel: "audit", level: "INFO", expected: false},
+		{order: []string{"debug", "info", "audit", "warn", "error"}, minLevel: "warn", level: "FATAL", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.order, ",")+"/"+tt.minLevel+"/"+tt.level, func(t *testing.T) {
+			config := &Config{LogLevels: levels, LevelOrder: tt.order}
+			sink := SinkConfig{Path: "./a.log", MinLevel: tt.minLevel}
+			if got := config.accepts(sink, tt.level); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConfigService_Sinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		update   ConfigUpdate
+		expected []SinkConfig
+		errorMsg string
+	}{
+		{
+			name:     "single log file is migrated",
+			existing: `{"log_file":"/tmp/team.log","log_levels":{"info":"i"}}`,
+			update:   ConfigUpdate{DefaultLevel: "info"},
+			expected: []SinkConfig{{Path: "/tmp/team.log"}},
+		},
+		{
+			name:     "add a sink",
+			existing: `{"log_file":"/tmp/team.log"}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Path: "./errors.log", MinLevel: "error"}}},
+			expected: []SinkConfig{{Path: "/tmp/team.log"}, {Path: "./errors.log", MinLevel: "error"}},
+		},
+		{
+			name:     "adding an existing path replaces it",
+			existing: `{"sinks":[{"path":"/tmp/team.log"},{"path":"./errors.log"}]}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Path: "./errors.log", Format: "jsonl"}}},
+			expected: []SinkConfig{{Path: "/tmp/team.log"}, {Path: "./errors.log", Format: "jsonl"}},
+		},
+		{
+			name:     "remove a sink",
+			existing: `{"sinks":[{"path":"/tmp/team.log"},{"path":"./errors.log"}]}`,
+			update:   ConfigUpdate{RemoveSinks: []string{"/tmp/team.log"}},
+			expected: []SinkConfig{{Path: "./errors.log"}},
+		},
+		{
+			name:     "file sets the main log file",
+			existing: `{"sinks":[{"path":"/tmp/team.log","format":"jsonl"},{"path":"./errors.log"}]}`,
+			update:   ConfigUpdate{LogFile: "/tmp/other.log"},
+			expected: []SinkConfig{{Path: "/tmp/other.log", Format: "jsonl"}, {Path: "./errors.log"}},
+		},
+		{
+			name:     "removing an unknown sink",
+			existing: `{"log_file":"/tmp/team.log"}`,
+			update:   ConfigUpdate{RemoveSinks: []string{"./missing.log"}},
+			errorMsg: "no sink writes to",
+		},
+		{
+			name:     "removing the last sink",
+			existing: `{"log_file":"/tmp/team.log"}`,
+			update:   ConfigUpdate{RemoveSinks: []string{"/tmp/team.log"}},
+			errorMsg: "at least one sink is required",
+		},
+		{
+			name:     "unknown minimum level",
+			existing: `{"log_file":"/tmp/team.log"}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Path: "./errors.log", MinLevel: "loud"}}},
+			errorMsg: "unknown minimum level",
+		},
+		{
+			name:     "minimum level from the level order",
+			existing: `{"log_file":"/tmp/team.log","log_levels":{"info":"i","audit":"a","error":"e"},"level_order":["info","audit","error"]}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Path: "./audit.log", MinLevel: "audit"}}},
+			expected: []SinkConfig{{Path: "/tmp/team.log"}, {Path: "./audit.log", MinLevel: "audit"}},
+		},
+		{
+			name:     "minimum level outside the level order",
+			existing: `{"log_file":"/tmp/team.log","log_levels":{"info":"i","error":"e"},"level_order":["info","error"]}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Path: "./errors.log", MinLevel: "warn"}}},
+			errorMsg: "use one of info, error",
+		},
+		{
+			name:     "invalid sink format",
+			existing: `{"log_file":"/tmp/team.log"}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Path: "./errors.log", Format: "xml"}}},
+			errorMsg: "format must be",
+		},
+		{
+			name:     "sink without a path",
+			existing: `{"log_file":"/tmp/team.log"}`,
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{MinLevel: "error"}}},
+			errorMsg: "requires a path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			mockFS.readFiles["/tmp/.slog/config.json"] = []byte(tt.existing)
+
+			configService := NewConfigService(mockFS, &MockPrinter{})
+			err := configService.UpdateConfig(tt.update)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var saved map[string]json.RawMessage
+			if err := json.Unmarshal(mockFS.writeFiles["/tmp/.slog/config.json"], &saved); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := saved["log_file"]; ok {
+				t.Error("Expected the saved config to have no log_file")
+			}
+
+			config, err := configService.LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(config.Sinks, tt.expected) {
+				t.Errorf("Expected sinks %+v, got %+v", tt.expected, config.Sinks)
+			}
+		})
+	}
+}
+
+func TestLogService_Sinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    string
+		expected map[string]string // substring each file must contain; "" for no file
+		printed  string
+	}{
+		{
+			name:  "error goes to every sink",
+			level: "error",
+			expected: map[string]string{
+				"/logs/team.log":   "ERROR: disk full",
+				"/logs/errors.log": `"level":"ERROR"`,
+			},
+			printed: "Logged to /logs/team.log, /logs/errors.log",
+		},
+		{
+			name:  "info skips the errors-only sink",
+			level: "info",
+			expected: map[string]string{
+				"/logs/team.log":   "INFO: disk full",
+				"/logs/errors.log": "",
+			},
+			printed: "Logged to /logs/team.log",
+		},
+		{
+			name:  "debug is below every sink",
+			level: "debug",
+			expected: map[string]string{
+				"/logs/team.log":   "",
+				"/logs/errors.log": "",
+			},
+			printed: "Nothing logged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				Sinks: []SinkConfig{
+					{Path: "/logs/team.log", MinLevel: "info"},
+					{Path: "/logs/errors.log", Format: FormatJSONL, MinLevel: "error"},
+				},
+				FileOrder: FileOrderOldestFirst,
+			}
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			if err := logService.AppendLog(tt.level, "disk full"); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := logService.Close(); err != nil {
+				t.Fatalf("Expected no error closing sinks, got %v", err)
+			}
+
+			for path, want := range tt.expected {
+				content, ok := mockFS.readFiles[path]
+				if want == "" {
+					if ok {
+						t.Errorf("Expected nothing written to %s, got %q", path, content)
+					}
+					continue
+				}
+				if !strings.Contains(string(content), want) {
+					t.Errorf("Expected %s to contain %q, got %q", path, want, content)
+				}
+			}
+			if !mockPrinter.ContainsMessage(tt.printed) {
+				t.Errorf("Expected output containing %q, got %v", tt.printed, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
+
+func TestLogService_SinkFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string // logged from a reader one line at a time when set
+		expected string
+		printed  string
+	}{
+		{
+			name:     "single entry",
+			expected: "INFO: hello",
+			printed:  "Logged to /logs/a.log, /logs/b.log",
+		},
+		{
+			name:     "per line",
+			input:    "x\ny\nz\n",
+			expected: "INFO: x\n",
+			printed:  "Logged 3 entries to /logs/a.log, /logs/b.log",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				Sinks: []SinkConfig{
+					{Path: "/logs/a.log"},
+					{Type: SinkSyslog, Address: "unix://" + filepath.Join(t.TempDir(), "missing.sock")},
+					{Path: "/logs/b.log"},
+				},
+				FileOrder: FileOrderOldestFirst,
+			}
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			var err error
+			if tt.input != "" {
+				err = logService.AppendFromReader("info", strings.NewReader(tt.input), nil, true)
+			} else {
+				err = logService.AppendLog("info", "hello")
+			}
+			if err == nil {
+				t.Fatalf("Expected the syslog failure to be returned")
+			}
+			if strings.Count(err.Error(), "\n") != 0 {
+				t.Errorf("Expected the failure to be reported once, got %v", err)
+			}
+			if err := logService.Close(); err != nil {
+				t.Fatalf("Expected no error closing sinks, got %v", err)
+			}
+
+			for _, path := range []string{"/logs/a.log", "/logs/b.log"} {
+				content := string(mockFS.readFiles[path])
+				if !strings.Contains(content, tt.expected) {
+					t.Errorf("Expected %s to contain %q, got %q", path, tt.expected, content)
+				}
+				if tt.input != "" && !strings.Contains(content, "INFO: z\n") {
+					t.Errorf("Expected %s to hold every line, got %q", path, content)
+				}
+			}
+			if !mockPrinter.ContainsMessage(tt.printed) {
+				t.Errorf("Expected output containing %q, got %v", tt.printed, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSyslogAddress is the local syslog socket a syslog sink uses when
+// it has no address.
+const defaultSyslogAddress = "/dev/log"
+
+// syslogTimeout bounds connecting to a syslog server and sending to it, so
+// an unreachable server cannot hang the command.
+const syslogTimeout = 5 * time.Second
+
+// syslogStructuredDataID names the structured data element that carries
+// entry fields. 32473 is the private enterprise number reserved for
+// documentation (RFC 5612).
+const syslogStructuredDataID = "slog@32473"
+
+// defaultSyslogFacility and defaultSyslogAppName are used when a syslog
+// sink does not set them.
+const (
+	defaultSyslogFacility = "user"
+	defaultSyslogAppName  = "slog"
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogEndpoint splits a syslog sink address into a network and an
+// address: 'udp://host:514', 'tcp://host:514', 'unix:///path' for a stream
+// socket, 'unixgram:///path' for a datagram socket, or a bare socket path.
+// A bare path has no network; it is tried as a datagram socket first.
+func syslogEndpoint(address string) (string, string, error) {
+	if address == "" {
+		return "", defaultSyslogAddress, nil
+	}
+	network, rest, ok := strings.Cut(address, "://")
+	if !ok {
+		return "", address, nil
+	}
+
+	switch network {
+	case "udp", "tcp":
+		if _, _, err := net.SplitHostPort(rest); err != nil {
+			return "", "", fmt.Errorf("invalid syslog address %q: %w", address, err)
+		}
+	case "unix", "unixgram":
+		if rest == "" {
+			return "", "", fmt.Errorf("invalid syslog address %q: missing socket path", address)
+		}
+	default:
+		return "", "", fmt.Errorf("invalid syslog address %q: use udp://, tcp://, unix://, unixgram:// or a socket path", address)
+	}
+	return network, rest, nil
+}
+
+// validateSyslogSink checks the settings of a syslog sink.
+func validateSyslogSink(s SinkConfig) error {
+	if _, _, err := syslogEndpoint(s.Address); err != nil {
+		return err
+	}
+	if s.Facility != "" {
+		if _, ok := syslogFacilities[s.Facility]; !ok {
+			return fmt.Errorf("unknown syslog facility %q: use kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp or local0 to local7", s.Facility)
+		}
+	}
+	if len(s.AppName) > 48 || strings.IndexFunc(s.AppName, func(r rune) bool { return r <= ' ' || r > '~' }) >= 0 {
+		return fmt.Errorf("invalid syslog app name %q: use at most 48 printable ASCII characters without spaces", s.AppName)
+	}
+	return nil
+}
+
+// syslogHeaderField makes value fit an RFC 5424 header field: printable
+// ASCII without spaces, at most limit characters, or "-" when empty.
+func syslogHeaderField(value string, limit int) string {
+	field := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(field) > limit {
+		field = field[:limit]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
+
+// syslogParamName makes a field key a valid structured data parameter
+// name.
+func syslogParamName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// formatSyslog renders an entry as an RFC 5424 message. Fields become
+// parameters of one structured data element.
+func formatSyslog(entry Entry, priority int, appName string, pid int) string {
+	structuredData := "-"
+	if len(entry.Fields) > 0 {
+		keys := make([]string, 0, len(entry.Fields))
+		for key := range entry.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var element strings.Builder
+		element.WriteString("[" + syslogStructuredDataID)
+		for _, key := range keys {
+			fmt.Fprintf(&element, ` %s="%s"`, syslogParamName(key), syslogParamEscaper.Replace(entry.Fields[key]))
+		}
+		element.WriteString("]")
+		structuredData = element.String()
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s",
+		priority,
+		entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(entry.Host, 255),
+		syslogHeaderField(appName, 48),
+		pid,
+		structuredData,
+		entry.Message,
+	)
+}
+
+// syslogSink sends entries to a syslog server. Stream connections frame
+// each message with its length (RFC 6587 octet counting); datagram
+// sockets send one message per datagram.
+type syslogSink struct {
+	config   *Config
+	sink     SinkConfig
+	network  string
+	address  string
+	facility int
+	conn     net.Conn
+}
+
+// newSyslogSink connects to the syslog server of a sink.
+func newSyslogSink(config *Config, sink SinkConfig) (*syslogSink, error) {
+	network, address, err := syslogEndpoint(sink.Address)
+	if err != nil {
+		return nil, err
+	}
+	facility := sink.Facility
+	if facility == "" {
+		facility = defaultSyslogFacility
+	}
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+
+	s := &syslogSink{config: config, sink: sink, network: network, address: address, facility: code}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials the server. A bare socket path is tried as a datagram
+// socket, then as a stream socket.
+func (s *syslogSink) connect() error {
+	networks := []string{s.network}
+	if s.network == "" {
+		networks = []string{"unixgram", "unix"}
+	}
+
+	var err error
+	for _, network := range networks {
+		var conn net.Conn
+		conn, err = net.DialTimeout(network, s.address, syslogTimeout)
+		if err == nil {
+			s.conn = conn
+			s.network = network
+			return nil
+		}
+	}
+	return fmt.Errorf("error connecting to syslog at %s: %w", s.sink.target(), err)
+}
+
+func (s *syslogSink) Write(entry Entry) (string, error) {
+	severity, ok := s.config.severity(entry.Level)
+	if !ok {
+		severity = levelSeverities["info"]
+	}
+	appName := s.sink.AppName
+	if appName == "" {
+		appName = defaultSyslogAppName
+	}
+
+	message := formatSyslog(entry, s.facility*8+severity, appName, os.Getpid())
+	if s.network == "tcp" || s.network == "unix" {
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+
+	err := s.send(message)
+	if err != nil && (s.network == "tcp" || s.network == "unix") {
+		// The server may have closed an idle stream; reconnect once
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+		err = s.send(message)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error sending to syslog at %s: %w", s.sink.target(), err)
+	}
+	return "syslog " + s.sink.target(), nil
+}
+
+// send writes one message, connecting first if the last attempt failed.
+func (s *syslogSink) send(message string) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write([]byte(message))
+	return err
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
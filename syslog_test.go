@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatSyslog(t *testing.T) {
+	stamp := time.Date(2024, 1, 15, 10, 30, 0, 123456000, time.UTC)
+
+	tests := []struct {
+		name     string
+		entry    Entry
+		expected string
+	}{
+		{
+			name:     "without fields",
+			entry:    Entry{Time: stamp, Level: "INFO", Message: "Application started", Host: "web-1"},
+			expected: "<14>1 2024-01-15T10:30:00.123456Z web-1 slog 42 - - Application started",
+		},
+		{
+			name: "fields as structured data",
+			entry: Entry{Time: stamp, Level: "INFO", Message: "Deploy failed", Host: "web-1", Fields: map[string]string{
+				"service": "api",
+				"note":    `say "hi" \ [ok]`,
+				"odd key": "x",
+			}},
+			expected: `<14>1 2024-01-15T10:30:00.123456Z web-1 slog 42 - [slog@32473 note="say \"hi\" \\ [ok\]" odd_key="x" service="api"] Deploy failed`,
+		},
+		{
+			name:     "missing host",
+			entry:    Entry{Time: stamp, Level: "INFO", Message: "hello"},
+			expected: "<14>1 2024-01-15T10:30:00.123456Z - slog 42 - - hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSyslog(tt.entry, 14, "slog", 42); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSyslogEndpoint(t *testing.T) {
+	tests := []struct {
+		address         string
+		expectedNetwork string
+		expectedAddress string
+		expectError     bool
+	}{
+		{address: "", expectedAddress: "/dev/log"},
+		{address: "/run/syslog.sock", expectedAddress: "/run/syslog.sock"},
+		{address: "udp://logs:514", expectedNetwork: "udp", expectedAddress: "logs:514"},
+		{address: "tcp://10.0.0.1:6514", expectedNetwork: "tcp", expectedAddress: "10.0.0.1:6514"},
+		{address: "unixgram:///dev/log", expectedNetwork: "unixgram", expectedAddress: "/dev/log"},
+		{address: "unix:///var/run/log", expectedNetwork: "unix", expectedAddress: "/var/run/log"},
+		{address: "udp://logs", expectError: true},
+		{address: "unix://", expectError: true},
+		{address: "http://logs:514", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			network, address, err := syslogEndpoint(tt.address)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %q %q", network, address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if network != tt.expectedNetwork || address != tt.expectedAddress {
+				t.Errorf("Expected %q %q, got %q %q", tt.expectedNetwork, tt.expectedAddress, network, address)
+			}
+		})
+	}
+}
+
+// syslogListener receives messages sent to a local syslog address.
+type syslogListener struct {
+	address  string
+	messages chan string
+}
+
+func listenSyslog(t *testing.T, network string) *syslogListener {
+	t.Helper()
+	listener := &syslogListener{messages: make(chan string, 10)}
+
+	switch network {
+	case "udp", "unixgram":
+		address := "127.0.0.1:0"
+		if network == "unixgram" {
+			address = filepath.Join(t.TempDir(), "log.sock")
+		}
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		listener.address = network + "://" + conn.LocalAddr().String()
+		go func() {
+			buf := make([]byte, 64*1024)
+			for {
+				n, _, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				listener.messages <- string(buf[:n])
+			}
+		}()
+	case "tcp":
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		listener.address = "tcp://" + ln.Addr().String()
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				length, err := reader.ReadString(' ')
+				if err != nil {
+					return
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(length))
+				if err != nil {
+					listener.messages <- "bad frame: " + length
+					return
+				}
+				message := make([]byte, n)
+				if _, err := io.ReadFull(reader, message); err != nil {
+					return
+				}
+				listener.messages <- string(message)
+			}
+		}()
+	}
+	return listener
+}
+
+func (l *syslogListener) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case message := <-l.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a syslog message, got none")
+		return ""
+	}
+}
+
+func TestLogService_SyslogSink(t *testing.T) {
+	for _, network := range []string{"udp", "tcp", "unixgram"} {
+		t.Run(network, func(t *testing.T) {
+			if network == "unixgram" && runtime.GOOS == "windows" {
+				t.Skip("Unix datagram sockets are not supported on Windows")
+			}
+			listener := listenSyslog(t, network)
+
+			config := Config{
+				Sinks: []SinkConfig{
+					{Path: "/logs/app.log"},
+					{Type: SinkSyslog, Address: listener.address, Facility: "local0", AppName: "deploy", MinLevel: "notice"},
+				},
+				LogLevels: map[string]LevelConfig{
+					"success": {Flag: "s", Severity: "notice"},
+				},
+				FileOrder: FileOrderOldestFirst,
+			}
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+
+			if err := logService.AppendLogWithFields("error", "Deploy failed", map[string]string{"service": "api"}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := logService.AppendLog("info", "below the minimum level"); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := logService.AppendLog("success", "Deploy finished"); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := logService.Close(); err != nil {
+				t.Fatalf("Expected no error closing sinks, got %v", err)
+			}
+
+			// local0 is facility 16; error is severity 3 and notice 5
+			first := listener.next(t)
+			if !strings.HasPrefix(first, "<131>1 ") || !strings.Contains(first, ` deploy `) ||
+				!strings.HasSuffix(first, `[slog@32473 service="api"] Deploy failed`) {
+				t.Errorf("Expected an RFC 5424 error message, got %q", first)
+			}
+			second := listener.next(t)
+			if !strings.HasPrefix(second, "<133>1 ") || !strings.HasSuffix(second, " - Deploy finished") {
+				t.Errorf("Expected the custom level as a notice, got %q", second)
+			}
+			if !mockPrinter.ContainsMessage("Logged to /logs/app.log, syslog " + listener.address) {
+				t.Errorf("Expected output naming both sinks, got %v", mockPrinter.GetMessages())
+			}
+		})
+	}
+}
+
+func TestLogService_SyslogSink_Unreachable(t *testing.T) {
+	config := Config{
+		Sinks:     []SinkConfig{{Type: SinkSyslog, Address: filepath.Join(t.TempDir(), "missing.sock")}},
+		FileOrder: FileOrderOldestFirst,
+	}
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+
+	configService := NewConfigService(mockFS, &MockPrinter{})
+	logService := NewLogService(configService, mockFS, &MockPrinter{})
+
+	err := logService.AppendLog("error", "Deploy failed")
+	if err == nil || !strings.Contains(err.Error(), "error connecting to syslog") {
+		t.Errorf("Expected a connection error, got %v", err)
+	}
+}
+
+func TestConfigService_SyslogSink(t *testing.T) {
+	tests := []struct {
+		name       string
+		update     ConfigUpdate
+		errorMsg   string
+		severities string
+	}{
+		{
+			name:   "syslog sink with defaults",
+			update: ConfigUpdate{AddSinks: []SinkConfig{{Type: SinkSyslog}}},
+		},
+		{
+			name:     "unknown facility",
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Type: SinkSyslog, Facility: "local9"}}},
+			errorMsg: "unknown syslog facility",
+		},
+		{
+			name:     "invalid address",
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Type: SinkSyslog, Address: "udp://logs"}}},
+			errorMsg: "invalid syslog address",
+		},
+		{
+			name:     "unknown sink type",
+			update:   ConfigUpdate{AddSinks: []SinkConfig{{Type: "carrier-pigeon", Path: "x"}}},
+			errorMsg: "unknown sink type",
+		},
+		{
+			name:       "severity of a custom level",
+			update:     ConfigUpdate{LogLevels: map[string]LevelConfig{"success": {Flag: "s"}}, Severities: map[string]string{"success": "notice"}},
+			severities: "success:notice",
+		},
+		{
+			name:     "severity of an unknown level",
+			update:   ConfigUpdate{Severities: map[string]string{"success": "notice"}},
+			errorMsg: "unknown level",
+		},
+		{
+			name:     "invalid severity",
+			update:   ConfigUpdate{LogLevels: map[string]LevelConfig{"success": {Flag: "s"}}, Severities: map[string]string{"success": "loud"}},
+			errorMsg: "invalid severity",
+		},
+		{
+			name:       "minimum level with a custom severity",
+			update:     ConfigUpdate{LogLevels: map[string]LevelConfig{"success": {Flag: "s", Severity: "5"}}, AddSinks: []SinkConfig{{Path: "./ok.log", MinLevel: "success"}}},
+			severities: "success:5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			mockFS.readFiles["/tmp/.slog/config.json"] = []byte(`{"log_file":"/tmp/team.log","log_levels":{"info":"i"}}`)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			err := configService.UpdateConfig(tt.update)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.severities != "" && !mockPrinter.ContainsMessage(tt.severities) {
+				t.Errorf("Expected output containing %q, got %v", tt.severities, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
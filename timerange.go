@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// TimeRange is the span of time 'slog view --since/--until' shows. A zero
+// bound leaves that end open; both bounds are included.
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// IsZero reports whether the range is open at both ends.
+func (r TimeRange) IsZero() bool {
+	return r.Since.IsZero() && r.Until.IsZero()
+}
+
+// Contains reports whether t falls within the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.Since.IsZero() || !t.Before(r.Since)) && (r.Until.IsZero() || !t.After(r.Until))
+}
+
+// widen returns the range extended by d at both ends. An open end stays
+// open.
+func (r TimeRange) widen(d time.Duration) TimeRange {
+	if !r.Since.IsZero() {
+		r.Since = r.Since.Add(-d)
+	}
+	if !r.Until.IsZero() {
+		r.Until = r.Until.Add(d)
+	}
+	return r
+}
+
+// timeBoundLayouts are the absolute times accepted by --since and --until,
+// besides the configured timestamp format. Layouts without a date are
+// taken as times of day today.
+var timeBoundLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"15:04:05",
+	"15:04",
+}
+
+// parseTimeBound parses a --since or --until value: 'now', 'today' or
+// 'yesterday' (both from midnight), a duration before now such as '2h',
+// '30m' or '3d', or an absolute time. Times without a zone are read in
+// location, the zone timestamps are written in.
+func parseTimeBound(value string, now time.Time, location *time.Location, stamp *TimestampFormat) (time.Time, error) {
+	now = now.In(location)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+	switch strings.ToLower(value) {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+	if age, err := parseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+
+	for _, layout := range timeBoundLayouts {
+		t, err := time.ParseInLocation(layout, value, location)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, location)
+		}
+		return t, nil
+	}
+	if stamp != nil {
+		if t, err := stamp.Parse(value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a time such as '2024-01-15 10:30', a duration such as '2h' or '3d', 'today' or 'yesterday'", value)
+}
+
+// timeRange resolves the --since and --until values of a view against the
+// config's time zone and timestamp format.
+func (o ViewOptions) timeRange(config *Config, now time.Time) (TimeRange, error) {
+	if o.Since == "" && o.Until == "" {
+		return TimeRange{}, nil
+	}
+	location, err := loadTimeZone(config.TimeZone)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	stamp, err := NewTimestampFormat(config.TimeFormat, config.TimeZone)
+	if err != nil {
+		return TimeRange{}, err
+	}
+
+	var r TimeRange
+	if o.Since != "" {
+		if r.Since, err = parseTimeBound(o.Since, now, location, stamp); err != nil {
+			return TimeRange{}, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if o.Until != "" {
+		if r.Until, err = parseTimeBound(o.Until, now, location, stamp); err != nil {
+			return TimeRange{}, fmt.Errorf("--until: %w", err)
+		}
+	}
+	if !r.Since.IsZero() && !r.Until.IsZero() && r.Since.After(r.Until) {
+		return TimeRange{}, fmt.Errorf("--since %s is after --until %s", r.Since.Format(time.DateTime), r.Until.Format(time.DateTime))
+	}
+	return r, nil
+}
+
+// search returns the part of a file, from start to end, that holds the
+// entries in the range. It relies on the entries being stored in time
+// order, as appending writes them, and finds each bound by binary search
+// so only a few records outside the range are read. Entries stored out of
+// order by up to some span are all found by searching the range widened
+// by it.
+func (r TimeRange) search(file io.ReaderAt, size int64, parser Formatter) (start, end int64, err error) {
+	start, end = 0, size
+	if !r.Since.IsZero() {
+		start, err = searchRecords(file, size, parser, func(t time.Time) bool { return !t.Before(r.Since) })
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if !r.Until.IsZero() {
+		end, err = searchRecords(file, size, parser, func(t time.Time) bool { return t.After(r.Until) })
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, max(start, end), nil
+}
+
+// searchRecords returns the offset of the first record in file whose
+// timestamp satisfies after, or size when there is none. after must hold
+// for every record from some point on. Lines that do not start an entry,
+// such as continuation lines, are passed over.
+func searchRecords(file io.ReaderAt, size int64, parser Formatter, after func(time.Time) bool) (int64, error) {
+	// Every record starting before lo fails after; every record starting
+	// at or after hi satisfies it.
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		offset, entry, ok, err := nextRecord(file, size, mid, hi, parser)
+		if err != nil {
+			return 0, err
+		}
+		if !ok || after(entry.Time) {
+			hi = mid
+		} else {
+			lo = offset + 1
+		}
+	}
+
+	offset, _, ok, err := nextRecord(file, size, lo, size, parser)
+	if err != nil || !ok {
+		return size, err
+	}
+	return offset, nil
+}
+
+// nextRecord finds the first entry in file, of size bytes, starting at or
+// after from and before limit, parsing only its first line.
+func nextRecord(file io.ReaderAt, size, from, limit int64, parser Formatter) (int64, Entry, bool, error) {
+	// A probe needs only a line or two; longer lines are still read whole
+	reader := bufio.NewReaderSize(io.NewSectionReader(file, from, size-from), 1024)
+	offset := from
+
+	// Begin at the next line unless from is the start of one
+	if from > 0 {
+		var previous [1]byte
+		if _, err := file.ReadAt(previous[:], from-1); err != nil {
+			return 0, Entry{}, false, err
+		}
+		if previous[0] != '\n' {
+			skipped, err := reader.ReadBytes('\n')
+			offset += int64(len(skipped))
+			if err == io.EOF {
+				return 0, Entry{}, false, nil
+			} else if err != nil {
+				return 0, Entry{}, false, err
+			}
+		}
+	}
+
+	for offset < limit {
+		line, err := reader.ReadBytes('\n')
+		text := strings.TrimRight(string(line), "\r\n")
+		if text != "" && !strings.HasPrefix(text, continuationPrefix) {
+			if entry, parseErr := parser.Parse(text); parseErr == nil {
+				return offset, entry, true, nil
+			}
+		}
+		offset += int64(len(line))
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, Entry{}, false, err
+		}
+	}
+	return 0, Entry{}, false, nil
+}
+
+// linesBefore counts the lines of file that end before offset, so a
+// record read from the middle of a file can be reported by line.
+func linesBefore(file io.ReaderAt, offset int64) (int, error) {
+	var lines int
+	buffer := make([]byte, 64*1024)
+	for position := int64(0); position < offset; {
+		n, err := file.ReadAt(buffer[:min(int64(len(buffer)), offset-position)], position)
+		lines += bytes.Count(buffer[:n], []byte{'\n'})
+		position += int64(n)
+		if err != nil && (err != io.EOF || position < offset) {
+			return 0, err
+		}
+	}
+	return lines, nil
+}
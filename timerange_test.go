@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	stamp, err := NewTimestampFormat(TimePresetUnix, TimeZoneUTC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		value    string
+		expected time.Time
+		errorMsg string
+	}{
+		{value: "now", expected: now},
+		{value: "today", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "Yesterday", expected: time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{value: "2h", expected: now.Add(-2 * time.Hour)},
+		{value: "90m", expected: now.Add(-90 * time.Minute)},
+		{value: "3d", expected: now.AddDate(0, 0, -3)},
+		{value: "2024-01-10 08:00:30", expected: time.Date(2024, 1, 10, 8, 0, 30, 0, time.UTC)},
+		{value: "2024-01-10 08:00", expected: time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)},
+		{value: "2024-01-10", expected: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-10T08:00:00+02:00", expected: time.Date(2024, 1, 10, 6, 0, 0, 0, time.UTC)},
+		{value: "09:15", expected: time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)},
+		{value: "1705310000", expected: time.Unix(1705310000, 0)},
+		{value: "last week", errorMsg: "invalid time"},
+		{value: "-2h", errorMsg: "invalid time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimeBound(tt.value, now, time.UTC, stamp)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// countingReaderAt records how many bytes are read through it
+type countingReaderAt struct {
+	io.ReaderAt
+	read int64
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.ReaderAt.ReadAt(p, off)
+	r.read += int64(n)
+	return n, err
+}
+
+func TestTimeRange_Search(t *testing.T) {
+	text, err := NewTextFormatter("", defaultTimestampFormat(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// An entry a minute from 00:00 on, every tenth with a continuation
+	// line and a damaged line after entry 500
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+	var log strings.Builder
+	var offsets []int64
+	for i := range 20000 {
+		offsets = append(offsets, int64(log.Len()))
+		fmt.Fprintf(&log, "[%s] INFO: entry %d\n", base.Add(time.Duration(i)*time.Minute).Format(time.DateTime), i)
+		if i%10 == 0 {
+			log.WriteString("  more detail\n")
+		}
+		if i == 500 {
+			log.WriteString("damaged line\n")
+		}
+	}
+	data := log.String()
+	size := int64(len(data))
+	at := func(minute int) time.Time { return base.Add(time.Duration(minute) * time.Minute) }
+
+	tests := []struct {
+		name          string
+		window        TimeRange
+		expectedStart int64
+		expectedEnd   int64
+	}{
+		{name: "since", window: TimeRange{Since: at(750)}, expectedStart: offsets[750], expectedEnd: size},
+		{name: "since between entries", window: TimeRange{Since: at(750).Add(30 * time.Second)}, expectedStart: offsets[751], expectedEnd: size},
+		{name: "since after a damaged line", window: TimeRange{Since: at(501)}, expectedStart: offsets[501], expectedEnd: size},
+		{name: "until", window: TimeRange{Until: at(20)}, expectedStart: 0, expectedEnd: offsets[21]},
+		{name: "both", window: TimeRange{Since: at(100), Until: at(199)}, expectedStart: offsets[100], expectedEnd: offsets[200]},
+		{name: "before the first entry", window: TimeRange{Since: at(-60)}, expectedStart: 0, expectedEnd: size},
+		{name: "after the last entry", window: TimeRange{Since: at(30000)}, expectedStart: size, expectedEnd: size},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &countingReaderAt{ReaderAt: strings.NewReader(data)}
+			start, end, err := tt.window.search(file, size, text)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if start != tt.expectedStart || end != tt.expectedEnd {
+				t.Errorf("Expected range %d-%d, got %d-%d", tt.expectedStart, tt.expectedEnd, start, end)
+			}
+			if file.read > size/10 {
+				t.Errorf("Expected the search to read a small part of %d bytes, got %d", size, file.read)
+			}
+		})
+	}
+}
+
+func TestLogService_ViewLog_TimeRange(t *testing.T) {
+	const log = "[2024-01-15 09:00:00] INFO: first\n" +
+		"[2024-01-15 10:00:00] WARN: second\n" +
+		"  with detail\n" +
+		"not an entry\n" +
+		"[2024-01-15 11:00:00] ERROR: third\n" +
+		"[2024-01-15 12:00:00] INFO: fourth\n"
+
+	tests := []struct {
+		name            string
+		writeMode       string
+		fileOrder       string
+		data            string
+		options         ViewOptions
+		expectedOutput  string
+		expectedWarning string
+		errorMsg        string
+	}{
+		{
+			name:            "since",
+			options:         ViewOptions{Since: "2024-01-15 10:30"},
+			expectedOutput:  "[2024-01-15 11:00:00] ERROR: third\n[2024-01-15 12:00:00] INFO: fourth\n",
+			expectedWarning: "",
+		},
+		{
+			name:            "until",
+			options:         ViewOptions{Until: "2024-01-15 10:00:00"},
+			expectedOutput:  "[2024-01-15 09:00:00] INFO: first\n[2024-01-15 10:00:00] WARN: second\n  with detail\n",
+			expectedWarning: "",
+		},
+		{
+			name:            "range reports malformed lines by line",
+			options:         ViewOptions{Since: "2024-01-15 10:00", Until: "2024-01-15 11:00"},
+			expectedOutput:  "[2024-01-15 10:00:00] WARN: second\n  with detail\n[2024-01-15 11:00:00] ERROR: third\n",
+			expectedWarning: "Skipped 1 records that could not be parsed, the first at /tmp/test.log:4",
+		},
+		{
+			name:           "with a level",
+			options:        ViewOptions{Since: "2024-01-15 09:30", Levels: []string{"info"}},
+			expectedOutput: "[2024-01-15 12:00:00] INFO: fourth\n",
+		},
+		{
+			name:           "prepend mode shows newest first",
+			writeMode:      "prepend",
+			fileOrder:      FileOrderOldestFirst,
+			options:        ViewOptions{Since: "2024-01-15 10:30"},
+			expectedOutput: "[2024-01-15 12:00:00] INFO: fourth\n[2024-01-15 11:00:00] ERROR: third\n",
+		},
+		{
+			name:           "old prepend layout is scanned",
+			writeMode:      "prepend",
+			data:           "[2024-01-15 12:00:00] INFO: fourth\n[2024-01-15 11:00:00] ERROR: third\n[2024-01-15 09:00:00] INFO: first\n",
+			options:        ViewOptions{Since: "2024-01-15 10:30"},
+			expectedOutput: "[2024-01-15 12:00:00] INFO: fourth\n[2024-01-15 11:00:00] ERROR: third\n",
+		},
+		{
+			name:           "entries stored out of order while waiting for the lock",
+			data:           "[2024-01-15 10:00:00] INFO: a\n[2024-01-15 10:00:06] INFO: b\n[2024-01-15 10:00:04] INFO: c\n[2024-01-15 10:00:07] INFO: d\n",
+			options:        ViewOptions{Since: "2024-01-15 10:00:05"},
+			expectedOutput: "[2024-01-15 10:00:06] INFO: b\n[2024-01-15 10:00:07] INFO: d\n",
+		},
+		{
+			name:     "invalid time",
+			options:  ViewOptions{Since: "soon"},
+			errorMsg: "--since: invalid time \"soon\"",
+		},
+		{
+			name:     "since after until",
+			options:  ViewOptions{Since: "2024-01-15 12:00", Until: "2024-01-15 10:00"},
+			errorMsg: "is after --until",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			fileOrder := tt.fileOrder
+			if tt.writeMode == "" {
+				fileOrder = FileOrderOldestFirst
+			}
+			config := Config{LogFile: "/tmp/test.log", WriteMode: tt.writeMode, FileOrder: fileOrder}
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			data := tt.data
+			if data == "" {
+				data = log
+			}
+			mockFS.readFiles["/tmp/test.log"] = []byte(data)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+			err := logService.ViewLog(tt.options)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Contains(mockPrinter.GetMessages(), tt.expectedOutput) {
+				t.Errorf("Expected output %q, got %v", tt.expectedOutput, mockPrinter.GetMessages())
+			}
+			if tt.expectedWarning != "" && !mockPrinter.ContainsMessage(tt.expectedWarning) {
+				t.Errorf("Expected warning %q, got %v", tt.expectedWarning, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp presets accepted in place of a Go time layout.
+const (
+	TimePresetRFC3339     = "rfc3339"
+	TimePresetRFC3339Nano = "rfc3339nano"
+	TimePresetUnix        = "unix"
+	TimePresetUnixMilli   = "unixmilli"
+	TimePresetUnixMicro   = "unixmicro"
+	TimePresetUnixNano    = "unixnano"
+)
+
+// Time zone names with a special meaning; anything else is looked up as an
+// IANA zone name such as "Europe/Berlin".
+const (
+	TimeZoneLocal = "local"
+	TimeZoneUTC   = "utc"
+)
+
+// TimestampFormat renders and parses entry timestamps using a configured
+// layout and zone.
+type TimestampFormat struct {
+	layout   string
+	unit     time.Duration // non-zero for Unix epoch presets
+	location *time.Location
+}
+
+// NewTimestampFormat resolves a layout or preset name and a zone name.
+// Empty values select the original "2006-01-02 15:04:05" local layout.
+func NewTimestampFormat(format, zone string) (*TimestampFormat, error) {
+	location, err := loadTimeZone(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TimestampFormat{location: location}
+	switch strings.ToLower(format) {
+	case "":
+		ts.layout = timestampLayout
+	case TimePresetRFC3339:
+		ts.layout = time.RFC3339
+	case TimePresetRFC3339Nano:
+		ts.layout = time.RFC3339Nano
+	case TimePresetUnix:
+		ts.unit = time.Second
+	case TimePresetUnixMilli:
+		ts.unit = time.Millisecond
+	case TimePresetUnixMicro:
+		ts.unit = time.Microsecond
+	case TimePresetUnixNano:
+		ts.unit = time.Nanosecond
+	default:
+		ts.layout = format
+	}
+
+	return ts, nil
+}
+
+// isEpochPreset reports whether format names one of the Unix epoch
+// presets, whose timestamps are numbers rather than readable times.
+func isEpochPreset(format string) bool {
+	switch strings.ToLower(format) {
+	case TimePresetUnix, TimePresetUnixMilli, TimePresetUnixMicro, TimePresetUnixNano:
+		return true
+	}
+	return false
+}
+
+func loadTimeZone(zone string) (*time.Location, error) {
+	switch strings.ToLower(zone) {
+	case "", TimeZoneLocal:
+		return time.Local, nil
+	case TimeZoneUTC:
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", zone, err)
+	}
+	return location, nil
+}
+
+func (ts *TimestampFormat) Format(t time.Time) string {
+	if ts.unit != 0 {
+		return strconv.FormatInt(t.UnixNano()/int64(ts.unit), 10)
+	}
+	return t.In(ts.location).Format(ts.layout)
+}
+
+func (ts *TimestampFormat) Parse(value string) (time.Time, error) {
+	if ts.unit != 0 {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", value, err)
+		}
+		return time.Unix(0, n*int64(ts.unit)).In(ts.location), nil
+	}
+
+	t, err := time.ParseInLocation(ts.layout, value, ts.location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTimestampFormat(t *testing.T) {
+	moment := time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name     string
+		format   string
+		zone     string
+		expected string
+	}{
+		{name: "default layout", format: "", zone: "utc", expected: "2024-01-15 10:30:00"},
+		{name: "rfc3339", format: "rfc3339", zone: "utc", expected: "2024-01-15T10:30:00Z"},
+		{name: "rfc3339nano", format: "rfc3339nano", zone: "utc", expected: "2024-01-15T10:30:00.123456789Z"},
+		{name: "preset names are case insensitive", format: "RFC3339", zone: "UTC", expected: "2024-01-15T10:30:00Z"},
+		{name: "unix seconds", format: "unix", zone: "utc", expected: "1705314600"},
+		{name: "unix milliseconds", format: "unixmilli", zone: "utc", expected: "1705314600123"},
+		{name: "unix microseconds", format: "unixmicro", zone: "utc", expected: "1705314600123456"},
+		{name: "unix nanoseconds", format: "unixnano", zone: "utc", expected: "1705314600123456789"},
+		{name: "custom layout with milliseconds", format: "2006-01-02 15:04:05.000", zone: "utc", expected: "2024-01-15 10:30:00.123"},
+		{name: "named zone", format: "2006-01-02 15:04:05 MST", zone: "Asia/Tokyo", expected: "2024-01-15 19:30:00 JST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stamp, err := NewTimestampFormat(tt.format, tt.zone)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := stamp.Format(moment); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+
+			parsed, err := stamp.Parse(tt.expected)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := stamp.Format(parsed); got != tt.expected {
+				t.Errorf("Expected %q to round-trip, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewTimestampFormat_UnknownZone(t *testing.T) {
+	_, err := NewTimestampFormat("", "Mars/Olympus_Mons")
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "unknown time zone") {
+		t.Errorf("Expected unknown time zone error, got %q", err.Error())
+	}
+}
+
+func TestTimestampFormat_Parse_Invalid(t *testing.T) {
+	for _, format := range []string{"", "unix"} {
+		stamp, err := NewTimestampFormat(format, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, err := stamp.Parse("yesterday"); err == nil {
+			t.Errorf("Expected error parsing invalid timestamp with format %q, got nil", format)
+		}
+	}
+}
+
+func TestValidateFormat_TimeFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeFormat  string
+		timeZone    string
+		expectError bool
+	}{
+		{name: "rfc3339nano", timeFormat: "rfc3339nano"},
+		{name: "unix epoch", timeFormat: "unixmilli"},
+		{name: "layout with zone", timeFormat: time.RFC1123Z, timeZone: "America/New_York"},
+		{name: "no date", timeFormat: "15:04:05", expectError: true},
+		{name: "no seconds", timeFormat: "2006-01-02 15:04", expectError: true},
+		{name: "12-hour clock without AM/PM", timeFormat: "2006-01-02 03:04:05", expectError: true},
+		{name: "not a layout", timeFormat: "timestamp", expectError: true},
+		{name: "unknown zone", timeZone: "Nowhere/Special", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, format := range []string{FormatText, FormatJSONL, FormatLogfmt} {
+				err := ValidateFormat(format, FormatOptions{TimeFormat: tt.timeFormat, TimeZone: tt.timeZone})
+				if tt.expectError && err == nil {
+					t.Errorf("Expected error for format %q, got nil", format)
+				}
+				if !tt.expectError && err != nil {
+					t.Errorf("Expected no error for format %q, got %v", format, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLogService_ViewLog_EpochTimestamps(t *testing.T) {
+	entry := Entry{Time: time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC), Level: "WARN", Message: "disk at 85%"}
+
+	tests := []struct {
+		name           string
+		format         string
+		timeFormat     string
+		template       string
+		expectedOutput string
+	}{
+		{name: "jsonl", format: FormatJSONL, timeFormat: TimePresetUnixNano, expectedOutput: "[2024-01-15 10:30:00] WARN: disk at 85%\n"},
+		{name: "logfmt", format: FormatLogfmt, timeFormat: TimePresetUnixMilli, expectedOutput: "[2024-01-15 10:30:00] WARN: disk at 85%\n"},
+		{name: "text", format: FormatText, timeFormat: TimePresetUnix, expectedOutput: "[2024-01-15 10:30:00] WARN: disk at 85%\n"},
+		{name: "text keeps its template", format: FormatText, timeFormat: TimePresetUnix, template: "{{.Time}} {{.Level}} {{.Message}}", expectedOutput: "2024-01-15 10:30:00 WARN disk at 85%\n"},
+		{name: "readable layouts are kept", format: FormatJSONL, timeFormat: TimePresetRFC3339, expectedOutput: "[2024-01-15T10:30:00Z] WARN: disk at 85%\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{LogFile: "/tmp/test.log", Format: tt.format, TimeFormat: tt.timeFormat, TimeZone: TimeZoneUTC, Template: tt.template, FileOrder: FileOrderOldestFirst}
+			formatter, err := NewFormatter(config.Format, config.FormatOptions())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			line, err := formatter.Format(entry)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configJSON, _ := json.Marshal(config)
+			mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+			mockFS.readFiles["/tmp/test.log"] = []byte(line)
+			mockPrinter := &MockPrinter{}
+
+			configService := NewConfigService(mockFS, mockPrinter)
+			logService := NewLogService(configService, mockFS, mockPrinter)
+			if err := logService.ViewLog(ViewOptions{}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !slices.Contains(mockPrinter.GetMessages(), tt.expectedOutput) {
+				t.Errorf("Expected output %q, got %v", tt.expectedOutput, mockPrinter.GetMessages())
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// webhookRecord is one entry in a webhook request body.
+type webhookRecord struct {
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Message   string            `json:"message"`
+	Host      string            `json:"host,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// encodeWebhook renders a batch as a JSON array of entries with UTC
+// RFC 3339 timestamps.
+func encodeWebhook(entries []Entry) ([]byte, string, error) {
+	records := make([]webhookRecord, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, webhookRecord{
+			Timestamp: entry.Time.UTC().Format(time.RFC3339Nano),
+			Level:     entry.Level,
+			Message:   entry.Message,
+			Host:      entry.Host,
+			Fields:    entry.Fields,
+		})
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, "application/json", nil
+}
+
+// newWebhookSink prepares a sink that POSTs entries to a URL as JSON.
+func newWebhookSink(fs FileSystem, sink SinkConfig) (*httpSink, error) {
+	return newHTTPSink(fs, sink, "webhook "+sink.URL, encodeWebhook, false)
+}
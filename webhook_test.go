@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncodeWebhook(t *testing.T) {
+	stamp := time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	entries := []Entry{
+		{Time: stamp, Level: "ERROR", Message: "Deploy failed", Host: "web-1", Fields: map[string]string{"service": "api"}},
+		{Time: stamp, Level: "INFO", Message: "Retrying"},
+	}
+
+	body, contentType, err := encodeWebhook(entries)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Expected application/json, got %q", contentType)
+	}
+	expected := `[{"timestamp":"2024-01-15T09:30:00.123456789Z","level":"ERROR","message":"Deploy failed","host":"web-1","fields":{"service":"api"}},` +
+		`{"timestamp":"2024-01-15T09:30:00.123456789Z","level":"INFO","message":"Retrying"}]`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+// webhookServer records the batches POSTed to it and answers with the
+// given statuses in turn, then 200.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	batches  [][]webhookRecord
+	headers  []http.Header
+	attempts int
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	t.Helper()
+	server := &webhookServer{statuses: statuses}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		server.attempts++
+		if len(server.statuses) > 0 {
+			status := server.statuses[0]
+			server.statuses = server.statuses[1:]
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				io.WriteString(w, "try again later")
+				return
+			}
+		}
+		var batch []webhookRecord
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("Expected a JSON array, got %v", err)
+		}
+		server.batches = append(server.batches, batch)
+		server.headers = append(server.headers, r.Header.Clone())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// messages returns the messages received, in order.
+func (s *webhookServer) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []string
+	for _, batch := range s.batches {
+		for _, record := range batch {
+			messages = append(messages, record.Message)
+		}
+	}
+	return messages
+}
+
+func newWebhookLogService(t *testing.T, mockFS *MockFileSystem, sink SinkConfig) (*LogService, *MockPrinter) {
+	t.Helper()
+	config := Config{
+		Sinks:     []SinkConfig{{Path: "/logs/app.log"}, sink},
+		FileOrder: FileOrderOldestFirst,
+	}
+	configJSON, _ := json.Marshal(config)
+	mockFS.readFiles["/tmp/.slog/config.json"] = configJSON
+	mockPrinter := &MockPrinter{}
+	configService := NewConfigService(mockFS, mockPrinter)
+	return NewLogService(configService, mockFS, mockPrinter), mockPrinter
+}
+
+func TestLogService_WebhookSink(t *testing.T) {
+	server := newWebhookServer(t)
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	sink := SinkConfig{Type: SinkWebhook, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer abc"}, BatchSize: 2}
+	logService, mockPrinter := newWebhookLogService(t, mockFS, sink)
+
+	input := strings.NewReader("first\nsecond\nthird\n")
+	if err := logService.AppendFromReader("info", input, map[string]string{"job": "backup"}, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(server.messages()) != 2 {
+		t.Errorf("Expected a full batch to be sent while writing, got %v", server.messages())
+	}
+	if err := logService.Close(); err != nil {
+		t.Fatalf("Expected no error closing sinks, got %v", err)
+	}
+
+	if got := strings.Join(server.messages(), ","); got != "first,second,third" {
+		t.Errorf("Expected first,second,third, got %s", got)
+	}
+	if len(server.batches) != 2 {
+		t.Errorf("Expected 2 batches, got %d", len(server.batches))
+	}
+	record := server.batches[0][0]
+	if record.Level != "INFO" || record.Fields["job"] != "backup" || record.Timestamp == "" {
+		t.Errorf("Expected the entry's level, fields and timestamp, got %+v", record)
+	}
+	for _, header := range server.headers {
+		if header.Get("Authorization") != "Bearer abc" || header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected the configured headers, got %v", header)
+		}
+	}
+	if !mockPrinter.ContainsMessage("Logged 3 entries to /logs/app.log, webhook " + server.URL) {
+		t.Errorf("Expected output naming both sinks, got %v", mockPrinter.GetMessages())
+	}
+}
+
+func TestLogService_WebhookSink_Retry(t *testing.T) {
+	defer func(backoff time.Duration) { httpRetryBackoff = backoff }(httpRetryBackoff)
+	httpRetryBackoff = time.Millisecond
+
+	server := newWebhookServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	logService, _ := newWebhookLogService(t, mockFS, SinkConfig{Type: SinkWebhook, URL: server.URL})
+
+	if err := logService.AppendLog("error", "Deploy failed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.Close(); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if server.attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", server.attempts)
+	}
+	if got := server.messages(); len(got) != 1 || got[0] != "Deploy failed" {
+		t.Errorf("Expected the entry once, got %v", got)
+	}
+}
+
+func TestLogService_WebhookSink_Spool(t *testing.T) {
+	defer func(backoff time.Duration) { httpRetryBackoff = backoff }(httpRetryBackoff)
+	httpRetryBackoff = time.Millisecond
+
+	server := newWebhookServer(t, http.StatusBadGateway, http.StatusBadGateway)
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	retries := 1
+	sink := SinkConfig{Type: SinkWebhook, URL: server.URL, Retries: &retries}
+	spool := spoolPath("/tmp", sink)
+
+	logService, _ := newWebhookLogService(t, mockFS, sink)
+	if err := logService.AppendLog("error", "Deploy failed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err := logService.Close()
+	if err == nil || !strings.Contains(err.Error(), "kept in "+spool) {
+		t.Fatalf("Expected the entry to be spooled, got %v", err)
+	}
+	if !strings.Contains(string(mockFS.readFiles[spool]), `"message":"Deploy failed"`) {
+		t.Errorf("Expected the entry in the spool file, got %q", mockFS.readFiles[spool])
+	}
+	if len(mockFS.readFiles["/logs/app.log"]) == 0 {
+		t.Errorf("Expected the file sink to be written regardless")
+	}
+
+	// The endpoint is back: the spooled entry goes first
+	logService, _ = newWebhookLogService(t, mockFS, sink)
+	if err := logService.AppendLog("info", "Deploy retried"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := logService.Close(); err != nil {
+		t.Fatalf("Expected no error closing sinks, got %v", err)
+	}
+	if got := strings.Join(server.messages(), ","); got != "Deploy failed,Deploy retried" {
+		t.Errorf("Expected the spooled entry before the new one, got %s", got)
+	}
+	if _, ok := mockFS.readFiles[spool]; ok {
+		t.Errorf("Expected the spool file to be removed, got %q", mockFS.readFiles[spool])
+	}
+}
+
+func TestLogService_WebhookSink_SpoolKeptUntilSent(t *testing.T) {
+	defer func(backoff time.Duration) { httpRetryBackoff = backoff }(httpRetryBackoff)
+	httpRetryBackoff = time.Millisecond
+
+	// The first batch is taken, then the endpoint goes down
+	server := newWebhookServer(t, http.StatusOK, http.StatusBadGateway, http.StatusBadGateway)
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	retries := 1
+	sink := SinkConfig{Type: SinkWebhook, URL: server.URL, BatchSize: 1, Retries: &retries}
+	spool := spoolPath("/tmp", sink)
+	mockFS.readFiles[spool] = []byte(`{"time":"2024-01-15T10:30:00Z","level":"ERROR","message":"first"}` + "\n" +
+		`{"time":"2024-01-15T10:31:00Z","level":"ERROR","message":"second"}` + "\n" +
+		`{"time":"2024-01-15T10:32:00Z","level":"ERROR","message":"third"}` + "\n")
+
+	logService, _ := newWebhookLogService(t, mockFS, sink)
+	if err := logService.AppendLog("info", "new"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err := logService.Close()
+	if err == nil || !strings.Contains(err.Error(), "3 entries could not be sent") {
+		t.Errorf("Expected the unsent entries to be reported, got %v", err)
+	}
+
+	if got := strings.Join(server.messages(), ","); got != "first" {
+		t.Errorf("Expected only the first entry to be taken, got %s", got)
+	}
+	var kept []string
+	for _, line := range strings.Split(strings.TrimSpace(string(mockFS.readFiles[spool])), "\n") {
+		var record spoolRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected spool records, got %q", line)
+		}
+		kept = append(kept, record.Message)
+	}
+	if got := strings.Join(kept, ","); got != "second,third,new" {
+		t.Errorf("Expected the spool to keep second,third,new, got %s", got)
+	}
+}
+
+func TestLogService_WebhookSink_Rejected(t *testing.T) {
+	server := newWebhookServer(t, http.StatusBadRequest)
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	sink := SinkConfig{Type: SinkWebhook, URL: server.URL}
+	logService, _ := newWebhookLogService(t, mockFS, sink)
+
+	if err := logService.AppendLog("error", "Deploy failed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err := logService.Close()
+	if err == nil || !strings.Contains(err.Error(), "dropped 1 entries") || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected the rejection to be reported, got %v", err)
+	}
+	if server.attempts != 1 {
+		t.Errorf("Expected a rejected request not to be retried, got %d attempts", server.attempts)
+	}
+	if _, ok := mockFS.readFiles[spoolPath("/tmp", sink)]; ok {
+		t.Errorf("Expected rejected entries not to be spooled")
+	}
+}
+
+func TestLogService_WebhookSink_NoRetries(t *testing.T) {
+	server := newWebhookServer(t, http.StatusBadGateway)
+	mockFS := NewMockFileSystem()
+	mockFS.homeDir = "/tmp"
+	retries := 0
+	sink := SinkConfig{Type: SinkWebhook, URL: server.URL, Retries: &retries}
+	logService, _ := newWebhookLogService(t, mockFS, sink)
+
+	if err := logService.AppendLog("error", "Deploy failed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err := logService.Close()
+	if err == nil || !strings.Contains(err.Error(), "kept in") {
+		t.Errorf("Expected the entry to be spooled, got %v", err)
+	}
+	if server.attempts != 1 {
+		t.Errorf("Expected a single attempt with retries=0, got %d", server.attempts)
+	}
+}
+
+func TestConfigService_WebhookSink(t *testing.T) {
+	two, negative := 2, -1
+	tests := []struct {
+		name     string
+		sink     SinkConfig
+		errorMsg string
+	}{
+		{name: "url only", sink: SinkConfig{Type: SinkWebhook, URL: "https://hooks.example.com/logs"}},
+		{name: "all settings", sink: SinkConfig{Type: SinkWebhook, URL: "http://localhost:8080", Headers: map[string]string{"X-Token": "abc"}, BatchSize: 10, Timeout: "2s", Retries: &two}},
+		{name: "missing url", sink: SinkConfig{Type: SinkWebhook}, errorMsg: "requires a url"},
+		{name: "not http", sink: SinkConfig{Type: SinkWebhook, URL: "ftp://hooks.example.com"}, errorMsg: "invalid url"},
+		{name: "bad header", sink: SinkConfig{Type: SinkWebhook, URL: "https://x.example.com", Headers: map[string]string{"X Token": "abc"}}, errorMsg: "invalid header name"},
+		{name: "bad timeout", sink: SinkConfig{Type: SinkWebhook, URL: "https://x.example.com", Timeout: "soon"}, errorMsg: "invalid timeout"},
+		{name: "negative batch", sink: SinkConfig{Type: SinkWebhook, URL: "https://x.example.com", BatchSize: -1}, errorMsg: "batch size"},
+		{name: "negative retries", sink: SinkConfig{Type: SinkWebhook, URL: "https://x.example.com", Retries: &negative}, errorMsg: "retries must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFS := NewMockFileSystem()
+			mockFS.homeDir = "/tmp"
+			configService := NewConfigService(mockFS, &MockPrinter{})
+
+			err := configService.UpdateConfig(ConfigUpdate{AddSinks: []SinkConfig{tt.sink}})
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		})
+	}
+}